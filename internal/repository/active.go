@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ActiveVersion names the pointer key that holds the version consumers
+// should use, independently of which version is the newest.
+const ActiveVersion = "_active"
+
+// Versions starting with an underscore are reserved for pointer keys and
+// are never listed as schemas.
+func isReservedVersion(version string) bool {
+	return strings.HasPrefix(version, "_")
+}
+
+// internalPrefixes hold the repository's own records, which are never
+// listed as schemas.
+var internalPrefixes = []string{idIndexPrefix, probePrefix, aliasPrefix}
+
+// internalKey returns the key of the record name under prefix that belongs
+// to org. Records are grouped by organization, so a tenant only ever sees
+// its own.
+func internalKey(prefix, org, name string) string {
+	return prefix + org + "/" + name
+}
+
+func (repo *EtcdRepository) isReservedKey(key string) bool {
+	for _, prefix := range internalPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	details, err := repo.getSchemaDetailsFromKey(key)
+	return err == nil && isReservedVersion(details.GetVersion())
+}
+
+func (repo *EtcdRepository) SetActiveVersion(ctx context.Context, org, namespace, name, version string) error {
+	ctx, span := repo.startSpan(ctx, "Repository.SetActiveVersion")
+	defer span.End()
+
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	if version == LatestVersion || isReservedVersion(version) {
+		return ErrReservedVersion
+	}
+	key := repo.getSchemaKey(org, namespace, name, version)
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), ">", 0)).
+		Then(clientv3.OpPut(repo.getSchemaKey(org, namespace, name, ActiveVersion), version)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !res.Succeeded {
+		return &SchemaNotFoundError{Key: key}
+	}
+	return nil
+}
+
+// PublishConfigSchema saves schema as a new version and makes it the
+// active one in a single transaction, also advancing the latest pointer
+// when the version is the newest. If the version already exists nothing
+// is written, so the active version never points at a missing schema.
+func (repo *EtcdRepository) PublishConfigSchema(ctx context.Context, org, namespace, name, version, schema string, opts ...SaveOption) error {
+	ctx, span := repo.startSpan(ctx, "Repository.PublishConfigSchema")
+	defer span.End()
+
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	options := newSaveOptions(opts)
+	options.activate = true
+	return repo.coalescedSave(ctx, repo.getSchemaKey(org, namespace, name, version), schema, options)
+}
+
+func (repo *EtcdRepository) GetActiveConfigSchema(ctx context.Context, org, namespace, name string) (*pb.ConfigSchema, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetActiveConfigSchema")
+	defer span.End()
+
+	pointerKey := repo.getSchemaKey(org, namespace, name, ActiveVersion)
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, pointerKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, &SchemaNotFoundError{Key: pointerKey}
+	}
+	key := repo.getSchemaKey(org, namespace, name, string(res.Kvs[0].Value))
+	res, err = repo.kv.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, &SchemaNotFoundError{Key: key}
+	}
+	return repo.decodeConfigSchema(res.Kvs[0])
+}
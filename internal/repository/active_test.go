@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+func TestActiveVersion(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v2.0.0")
+
+	if _, err := repo.GetActiveConfigSchema(ctx, "org", "ns", "name"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("unset pointer: got %v, want ErrSchemaNotFound", err)
+	}
+	for _, version := range []string{"v2.0.0", "v1.0.0"} {
+		if err := repo.SetActiveVersion(ctx, "org", "ns", "name", version); err != nil {
+			t.Fatalf("SetActiveVersion(%s): %v", version, err)
+		}
+		active, err := repo.GetActiveConfigSchema(ctx, "org", "ns", "name")
+		if err != nil || active.GetSchemaDetails().GetVersion() != version {
+			t.Errorf("got %v, %v, want %s", active.GetSchemaDetails(), err, version)
+		}
+	}
+
+	if err := repo.SetActiveVersion(ctx, "org", "ns", "name", "v3.0.0"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing target: got %v, want ErrSchemaNotFound", err)
+	}
+	if err := repo.SetActiveVersion(ctx, "org", "ns", "name", ActiveVersion); !errors.Is(err, ErrReservedVersion) {
+		t.Errorf("reserved target: got %v, want ErrReservedVersion", err)
+	}
+	if active, err := repo.GetActiveConfigSchema(ctx, "org", "ns", "name"); err != nil || active.GetSchemaDetails().GetVersion() != "v1.0.0" {
+		t.Errorf("failed repoints moved the pointer to %v, %v", active.GetSchemaDetails(), err)
+	}
+}
+
+func TestActivePointerIsNotAVersion(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0")
+	if err := repo.SetActiveVersion(ctx, "org", "ns", "name", "v1.0.0"); err != nil {
+		t.Fatalf("SetActiveVersion: %v", err)
+	}
+	if fake.get("org/ns/name/"+ActiveVersion) == nil {
+		t.Fatalf("no pointer key; stored keys are %v", fake.keys())
+	}
+
+	schemas, err := repo.GetSchemasByPrefix(ctx, "org/ns/name/")
+	if err != nil {
+		t.Fatalf("GetSchemasByPrefix: %v", err)
+	}
+	if got := schemaVersions(schemas); !slices.Equal(got, []string{"v1.0.0", "v1.1.0"}) {
+		t.Errorf("listing: got %v", got)
+	}
+	if latest, err := repo.GetLatestVersionByPrefix(ctx, "org/ns/name/"); err != nil || latest != "v1.1.0" {
+		t.Errorf("latest: got %q, %v", latest, err)
+	}
+	if oldest, err := repo.GetOldestVersionByPrefix(ctx, "org/ns/name/"); err != nil || oldest != "v1.0.0" {
+		t.Errorf("oldest: got %q, %v", oldest, err)
+	}
+}
+
+func TestPublishConfigSchema(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+
+	for _, version := range []string{"v1.0.0", "v1.1.0"} {
+		txns := fake.callCount("Txn")
+		if err := repo.PublishConfigSchema(ctx, "org", "ns", "name", version, testSchema); err != nil {
+			t.Fatalf("PublishConfigSchema(%s): %v", version, err)
+		}
+		if got := fake.callCount("Txn") - txns; got != 1 {
+			t.Errorf("%s: %d transactions, want 1", version, got)
+		}
+		stored := fake.get("org/ns/name/" + version)
+		active := fake.get("org/ns/name/" + ActiveVersion)
+		latest := fake.get("org/ns/name/" + latestPointerVersion)
+		if stored == nil || active == nil || latest == nil {
+			t.Fatalf("%s: missing keys; stored keys are %v", version, fake.keys())
+		}
+		if string(active.Value) != version || string(latest.Value) != version {
+			t.Errorf("%s: active %q, latest %q", version, active.Value, latest.Value)
+		}
+		if active.ModRevision != stored.ModRevision || latest.ModRevision != stored.ModRevision {
+			t.Errorf("%s: written at revisions %d, %d and %d, want one", version, stored.ModRevision, active.ModRevision, latest.ModRevision)
+		}
+	}
+	if fake.callCount("Put") != 0 {
+		t.Errorf("%d writes outside a transaction", fake.callCount("Put"))
+	}
+
+	// An older version becomes active but leaves the latest pointer alone.
+	if err := repo.PublishConfigSchema(ctx, "org", "ns", "name", "v0.9.0", testSchema); err != nil {
+		t.Fatalf("PublishConfigSchema(v0.9.0): %v", err)
+	}
+	if active, err := repo.GetActiveConfigSchema(ctx, "org", "ns", "name"); err != nil || active.GetSchemaDetails().GetVersion() != "v0.9.0" {
+		t.Errorf("active: got %v, %v, want v0.9.0", active.GetSchemaDetails(), err)
+	}
+	if latest := fake.get("org/ns/name/" + latestPointerVersion); string(latest.Value) != "v1.1.0" {
+		t.Errorf("latest: got %q, want v1.1.0", latest.Value)
+	}
+}
+
+func TestPublishConfigSchemaConflictKeepsPointers(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	if err := repo.PublishConfigSchema(ctx, "org", "ns", "name", "v1.0.0", testSchema); err != nil {
+		t.Fatalf("PublishConfigSchema: %v", err)
+	}
+
+	if err := repo.PublishConfigSchema(ctx, "org", "ns", "name", "v1.0.0", "type: string\n"); !errors.Is(err, ErrSchemaExists) {
+		t.Errorf("existing version: got %v, want ErrSchemaExists", err)
+	}
+
+	// The version appears between the existence check and the transaction,
+	// so only the transaction's guard catches it.
+	arrived, release := fake.hold("Txn")
+	errs := make(chan error, 1)
+	go func() { errs <- repo.PublishConfigSchema(ctx, "org", "ns", "name", "v2.0.0", testSchema) }()
+	<-arrived
+	stored, err := repo.encodeSchemaData(&pb.ConfigSchemaData{Schema: `{"type":"string"}`})
+	if err != nil {
+		t.Fatalf("encodeSchemaData: %v", err)
+	}
+	raced := fake.put("org/ns/name/v2.0.0", string(stored))
+	release()
+	if err := <-errs; !errors.Is(err, ErrSchemaExists) {
+		t.Errorf("racing version: got %v, want ErrSchemaExists", err)
+	}
+
+	if got := fake.get("org/ns/name/v2.0.0"); got.ModRevision != raced {
+		t.Errorf("the racing write was overwritten at revision %d", got.ModRevision)
+	}
+	for _, pointer := range []string{ActiveVersion, latestPointerVersion} {
+		if got := fake.get("org/ns/name/" + pointer); got == nil || string(got.Value) != "v1.0.0" {
+			t.Errorf("%s moved to %v after a failed publish", pointer, got)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+// aliasPrefix holds alias records, each mapping a friendly name to the key
+// prefix of a schema. Aliases are per organization and may only point at
+// schemas of their own organization.
+const aliasPrefix = "_alias/"
+
+// SetAlias points alias of org at the schema whose versions live under
+// targetKeyPrefix, e.g. "org/ns/name/", replacing any previous target.
+// Alias names follow the same rules as schema names.
+func (repo *EtcdRepository) SetAlias(ctx context.Context, org, alias, targetKeyPrefix string) error {
+	ctx, span := repo.startSpan(ctx, "Repository.SetAlias")
+	defer span.End()
+
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	if !repo.segmentPattern.MatchString(alias) {
+		return &InvalidSegmentError{Segment: "alias", Value: alias, Pattern: repo.segmentPattern.String()}
+	}
+	if _, err := repo.parseAliasTarget(org, targetKeyPrefix); err != nil {
+		return err
+	}
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	_, err := repo.kv.Put(ctx, internalKey(aliasPrefix, org, alias), targetKeyPrefix)
+	return err
+}
+
+func (repo *EtcdRepository) DeleteAlias(ctx context.Context, org, alias string) error {
+	ctx, span := repo.startSpan(ctx, "Repository.DeleteAlias")
+	defer span.End()
+
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	_, err := repo.kv.Delete(ctx, internalKey(aliasPrefix, org, alias))
+	return err
+}
+
+// ResolveAlias returns the key prefix alias of org points at.
+func (repo *EtcdRepository) ResolveAlias(ctx context.Context, org, alias string) (string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.ResolveAlias")
+	defer span.End()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, internalKey(aliasPrefix, org, alias))
+	if err != nil {
+		return "", err
+	}
+	if len(res.Kvs) == 0 {
+		return "", fmt.Errorf("%w: '%s'", ErrAliasNotFound, alias)
+	}
+	return string(res.Kvs[0].Value), nil
+}
+
+// GetConfigSchemaByAlias is GetConfigSchema for the given version of the
+// schema alias of org points at.
+func (repo *EtcdRepository) GetConfigSchemaByAlias(ctx context.Context, org, alias, version string) (*pb.ConfigSchemaData, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetConfigSchemaByAlias")
+	defer span.End()
+
+	details, err := repo.resolveAliasDetails(ctx, org, alias)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetConfigSchema(ctx, repo.getSchemaKey(details.GetOrganization(), details.GetNamespace(), details.GetSchemaName(), version))
+}
+
+// GetLatestConfigSchemaByAlias is GetLatestConfigSchema for the schema
+// alias of org points at.
+func (repo *EtcdRepository) GetLatestConfigSchemaByAlias(ctx context.Context, org, alias string) (*pb.ConfigSchema, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetLatestConfigSchemaByAlias")
+	defer span.End()
+
+	details, err := repo.resolveAliasDetails(ctx, org, alias)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetLatestConfigSchema(ctx, details.GetOrganization(), details.GetNamespace(), details.GetSchemaName())
+}
+
+func (repo *EtcdRepository) resolveAliasDetails(ctx context.Context, org, alias string) (*pb.ConfigSchemaDetails, error) {
+	target, err := repo.ResolveAlias(ctx, org, alias)
+	if err != nil {
+		return nil, err
+	}
+	return repo.parseAliasTarget(org, target)
+}
+
+// parseAliasTarget parses target, the key prefix of a schema, and checks
+// that the schema belongs to org.
+func (repo *EtcdRepository) parseAliasTarget(org, target string) (*pb.ConfigSchemaDetails, error) {
+	details, err := repo.parseSchemaPrefix(target)
+	if err != nil {
+		return nil, err
+	}
+	if details.GetOrganization() != org {
+		return nil, fmt.Errorf("%w: '%s' is not a schema of organization '%s'", ErrInvalidKey, target, org)
+	}
+	return details, nil
+}
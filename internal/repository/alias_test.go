@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.2.0")
+	mustSave(t, repo, "org/ns/other/v3.0.0")
+
+	if err := repo.SetAlias(ctx, "org", "payments-prod", "org/ns/name/"); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+	if target, err := repo.ResolveAlias(ctx, "org", "payments-prod"); err != nil || target != "org/ns/name/" {
+		t.Errorf("ResolveAlias: got %q, %v", target, err)
+	}
+	if schemaData, err := repo.GetConfigSchemaByAlias(ctx, "org", "payments-prod", "v1.0.0"); err != nil || schemaData == nil {
+		t.Errorf("GetConfigSchemaByAlias: got %v, %v", schemaData, err)
+	}
+	latest, err := repo.GetLatestConfigSchemaByAlias(ctx, "org", "payments-prod")
+	if err != nil || latest.GetSchemaDetails().GetVersion() != "v1.2.0" {
+		t.Errorf("GetLatestConfigSchemaByAlias: got %v, %v, want v1.2.0", latest.GetSchemaDetails(), err)
+	}
+	mustSave(t, repo, "org/ns/name/v2.0.0")
+	latest, err = repo.GetLatestConfigSchemaByAlias(ctx, "org", "payments-prod")
+	if err != nil || latest.GetSchemaDetails().GetVersion() != "v2.0.0" {
+		t.Errorf("latest after a save: got %v, %v, want v2.0.0", latest.GetSchemaDetails(), err)
+	}
+
+	// The alias record is not a schema.
+	if fake.get(internalKey(aliasPrefix, "org", "payments-prod")) == nil {
+		t.Fatalf("no alias record; stored keys are %v", fake.keys())
+	}
+	if schemas, err := repo.GetSchemasByPrefix(ctx, ""); err != nil || len(schemas) != 4 {
+		t.Errorf("listing: got %d schemas, %v, want 4", len(schemas), err)
+	}
+
+	if err := repo.SetAlias(ctx, "org", "payments-prod", "org/ns/other/"); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+	latest, err = repo.GetLatestConfigSchemaByAlias(ctx, "org", "payments-prod")
+	if err != nil || latest.GetSchemaDetails().GetSchemaName() != "other" {
+		t.Errorf("repointed alias: got %v, %v", latest.GetSchemaDetails(), err)
+	}
+
+	if err := repo.DeleteAlias(ctx, "org", "payments-prod"); err != nil {
+		t.Fatalf("DeleteAlias: %v", err)
+	}
+	if _, err := repo.ResolveAlias(ctx, "org", "payments-prod"); !errors.Is(err, ErrAliasNotFound) {
+		t.Errorf("deleted alias: got %v, want ErrAliasNotFound", err)
+	}
+	if _, err := repo.GetLatestConfigSchemaByAlias(ctx, "org", "missing"); !errors.Is(err, ErrAliasNotFound) {
+		t.Errorf("unknown alias: got %v, want ErrAliasNotFound", err)
+	}
+	if err := repo.SetAlias(ctx, "org", "Payments Prod", "org/ns/name/"); !errors.Is(err, ErrInvalidSegment) {
+		t.Errorf("invalid alias name: got %v, want ErrInvalidSegment", err)
+	}
+	if err := repo.SetAlias(ctx, "org", "payments-prod", "org/ns/"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("target that is not a schema: got %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestAliasesUnderTenant(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := WithTenant(context.Background(), "acme")
+	mustSave(t, repo, "acme/ns/name/v1.0.0")
+	mustSave(t, repo, "globex/ns/name/v9.0.0")
+	if err := repo.SetAlias(context.Background(), "globex", "pay", "globex/ns/name/"); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+
+	if err := repo.SetAlias(ctx, "acme", "pay", "acme/ns/name/"); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+	latest, err := repo.GetLatestConfigSchemaByAlias(ctx, "acme", "pay")
+	if err != nil || latest.GetSchemaDetails().GetOrganization() != "acme" {
+		t.Errorf("own alias: got %v, %v", latest.GetSchemaDetails(), err)
+	}
+
+	// Aliases can only target schemas of their own organization.
+	puts := fake.callCount("Put")
+	if err := repo.SetAlias(ctx, "acme", "pay", "globex/ns/name/"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("cross-organization target: got %v, want ErrInvalidKey", err)
+	}
+	if fake.callCount("Put") != puts {
+		t.Errorf("a cross-organization alias was written")
+	}
+	// A record pointing elsewhere, written around the repository, is
+	// refused on read as well.
+	fake.put(internalKey(aliasPrefix, "acme", "evil"), "globex/ns/name/")
+	if _, err := repo.GetLatestConfigSchemaByAlias(ctx, "acme", "evil"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("tampered record: got %v, want ErrInvalidKey", err)
+	}
+
+	// Another organization's aliases are out of reach.
+	for name, operation := range map[string]func() error{
+		"SetAlias": func() error {
+			return repo.SetAlias(ctx, "globex", "pay", "globex/ns/name/")
+		},
+		"ResolveAlias": func() error {
+			_, err := repo.ResolveAlias(ctx, "globex", "pay")
+			return err
+		},
+		"GetLatestConfigSchemaByAlias": func() error {
+			_, err := repo.GetLatestConfigSchemaByAlias(ctx, "globex", "pay")
+			return err
+		},
+		"DeleteAlias": func() error {
+			return repo.DeleteAlias(ctx, "globex", "pay")
+		},
+	} {
+		if err := operation(); !errors.Is(err, ErrTenantMismatch) {
+			t.Errorf("%s: got %v, want ErrTenantMismatch", name, err)
+		}
+	}
+	if target, err := repo.ResolveAlias(context.Background(), "globex", "pay"); err != nil || target != "globex/ns/name/" {
+		t.Errorf("globex alias: got %q, %v", target, err)
+	}
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+type ArchiveOption func(*archiveOptions)
+
+type archiveOptions struct {
+	gzip bool
+}
+
+// WithGzip compresses the archive written by ExportSchemaArchive.
+func WithGzip() ArchiveOption {
+	return func(options *archiveOptions) {
+		options.gzip = true
+	}
+}
+
+// ExportSchemaArchive streams every version of one schema to w as a tar
+// archive. Each version contributes "<version>.yaml" with its body and
+// "<version>.meta.json" with its details and metadata, both stamped with
+// the version's creation time. Versions that cannot safely name a file,
+// e.g. containing "/" under a custom key codec, fail the export with
+// ErrUnsafeArchiveEntry.
+func (repo *EtcdRepository) ExportSchemaArchive(ctx context.Context, org, namespace, name string, w io.Writer, opts ...ArchiveOption) error {
+	ctx, span := repo.startSpan(ctx, "Repository.ExportSchemaArchive")
+	defer span.End()
+
+	options := &archiveOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	var compressed *gzip.Writer
+	if options.gzip {
+		compressed = gzip.NewWriter(w)
+		w = compressed
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	archive := tar.NewWriter(w)
+	schemas, errs := repo.StreamAll(ctx, repo.getSchemaPrefix(org, namespace, name))
+	for schema := range schemas {
+		if err := writeArchiveEntries(archive, schema); err != nil {
+			return err
+		}
+	}
+	if err := <-errs; err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	if compressed != nil {
+		return compressed.Close()
+	}
+	return nil
+}
+
+func writeArchiveEntries(archive *tar.Writer, schema *pb.ConfigSchema) error {
+	metadata := proto.Clone(schema).(*pb.ConfigSchema)
+	metadata.GetSchemaData().Schema = ""
+	metadataJson, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	version := schema.GetSchemaDetails().GetVersion()
+	if !isSafeEntryName(version) {
+		return fmt.Errorf("%w: '%s'", ErrUnsafeArchiveEntry, version)
+	}
+	modTime := schema.GetSchemaData().GetCreationTime().AsTime()
+	files := []struct {
+		name string
+		body []byte
+	}{
+		{version + ".yaml", []byte(schema.GetSchemaData().GetSchema())},
+		{version + ".meta.json", metadataJson},
+	}
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    file.name,
+			Mode:    0644,
+			Size:    int64(len(file.body)),
+			ModTime: modTime,
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write(file.body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isSafeEntryName reports whether version can name archive entries without
+// them landing outside the extraction directory: it must not be empty, a
+// dot path or contain a path separator.
+func isSafeEntryName(version string) bool {
+	return version != "" && version != "." && version != ".." && !strings.ContainsAny(version, `/\`)
+}
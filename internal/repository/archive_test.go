@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+type archiveEntry struct {
+	header *tar.Header
+	body   []byte
+}
+
+func readArchive(t *testing.T, r io.Reader) []archiveEntry {
+	t.Helper()
+	archive := tar.NewReader(r)
+	var entries []archiveEntry
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("reading the archive: %v", err)
+		}
+		body, err := io.ReadAll(archive)
+		if err != nil {
+			t.Fatalf("reading %s: %v", header.Name, err)
+		}
+		entries = append(entries, archiveEntry{header: header, body: body})
+	}
+}
+
+func TestExportSchemaArchive(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	created := time.Date(2023, 11, 2, 10, 0, 0, 0, time.UTC)
+	mustSave(t, repo, "org/ns/name/v1.0.0", WithCreationTime(created), WithAuthor("ana"))
+	if err := repo.SaveConfigSchema(ctx, "org/ns/name/v1.1.0", "type: string\n", WithCreationTime(created.Add(time.Hour))); err != nil {
+		t.Fatalf("SaveConfigSchema: %v", err)
+	}
+	mustSave(t, repo, "org/ns/other/v9.0.0")
+
+	var buf bytes.Buffer
+	if err := repo.ExportSchemaArchive(ctx, "org", "ns", "name", &buf, WithGzip()); err != nil {
+		t.Fatalf("ExportSchemaArchive: %v", err)
+	}
+	unzipped, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("the archive is not gzipped: %v", err)
+	}
+	entries := readArchive(t, unzipped)
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.header.Name)
+	}
+	if want := []string{"v1.0.0.yaml", "v1.0.0.meta.json", "v1.1.0.yaml", "v1.1.0.meta.json"}; !slices.Equal(names, want) {
+		t.Fatalf("got entries %v, want %v", names, want)
+	}
+	for i, want := range map[int]string{0: testSchema, 2: "type: string\n"} {
+		if string(entries[i].body) != want {
+			t.Errorf("%s: got %q, want %q", names[i], entries[i].body, want)
+		}
+	}
+	var metadata pb.ConfigSchema
+	if err := json.Unmarshal(entries[1].body, &metadata); err != nil {
+		t.Fatalf("%s: %v", names[1], err)
+	}
+	if metadata.GetSchemaDetails().GetVersion() != "v1.0.0" || metadata.GetSchemaData().GetAuthor() != "ana" || metadata.GetSchemaData().GetSchema() != "" {
+		t.Errorf("%s: got %v", names[1], &metadata)
+	}
+	for _, entry := range entries[:2] {
+		if !entry.header.ModTime.Equal(created) {
+			t.Errorf("%s: modified %v, want the creation time %v", entry.header.Name, entry.header.ModTime, created)
+		}
+	}
+
+	// Without WithGzip the archive is a plain tar.
+	buf.Reset()
+	if err := repo.ExportSchemaArchive(ctx, "org", "ns", "name", &buf); err != nil {
+		t.Fatalf("ExportSchemaArchive: %v", err)
+	}
+	if entries := readArchive(t, &buf); len(entries) != 4 {
+		t.Errorf("plain tar: got %d entries, want 4", len(entries))
+	}
+}
+
+func TestExportSchemaArchiveRejectsUnsafeEntryNames(t *testing.T) {
+	// With "." as the delimiter the version is everything after the third
+	// dot, so keys written around the repository can hold any name.
+	for _, version := range []string{"..", "nested/v1", `..\v1`} {
+		repo, fake := newTestRepo(t, WithKeyCodec(DelimitedKeyCodec{Delimiter: "."}))
+		stored, err := repo.encodeSchemaData(&pb.ConfigSchemaData{Schema: `{"type":"object"}`})
+		if err != nil {
+			t.Fatalf("encodeSchemaData: %v", err)
+		}
+		fake.put("org.ns.name."+version, string(stored))
+
+		var buf bytes.Buffer
+		err = repo.ExportSchemaArchive(context.Background(), "org", "ns", "name", &buf)
+		if !errors.Is(err, ErrUnsafeArchiveEntry) {
+			t.Errorf("version %q: got %v, want ErrUnsafeArchiveEntry", version, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const bulkRetryBackoff = 100 * time.Millisecond
+
+// BulkResult reports the outcome of each entry of a best-effort bulk
+// write. Conflicted holds keys that already existed or kept being modified
+// concurrently; Failed holds every other error by key.
+type BulkResult struct {
+	Succeeded  []string
+	Conflicted []string
+	Failed     map[string]error
+}
+
+// SaveConfigSchemasBestEffort saves every key/schema pair of schemas on
+// its own, retrying transient etcd errors with exponential backoff. One
+// bad entry never fails the call; the error is only set when ctx ends
+// before all entries were attempted, with the remaining keys left out of
+// the result.
+func (repo *EtcdRepository) SaveConfigSchemasBestEffort(ctx context.Context, schemas map[string]string, opts ...SaveOption) (BulkResult, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.SaveConfigSchemasBestEffort")
+	defer span.End()
+
+	result := BulkResult{Failed: make(map[string]error)}
+	if repo.readOnly {
+		return result, ErrReadOnly
+	}
+	keys := make([]string, 0, len(schemas))
+	for key := range schemas {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		err := repo.saveWithBackoff(ctx, key, schemas[key], opts)
+		switch {
+		case err == nil:
+			result.Succeeded = append(result.Succeeded, key)
+		case errors.Is(err, ErrSchemaExists), errors.Is(err, ErrConcurrentModification):
+			result.Conflicted = append(result.Conflicted, key)
+		default:
+			result.Failed[key] = err
+		}
+	}
+	return result, nil
+}
+
+func (repo *EtcdRepository) saveWithBackoff(ctx context.Context, key, schema string, opts []SaveOption) error {
+	backoff := bulkRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := repo.SaveConfigSchema(ctx, key, schema, opts...)
+		if err == nil || attempt == maxModifyAttempts || !isTransient(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// isTransient reports whether err is an etcd failure worth retrying, as
+// opposed to one caused by the request itself.
+func isTransient(err error) bool {
+	code := status.Code(err)
+	var etcdErr rpctypes.EtcdError
+	if errors.As(err, &etcdErr) {
+		code = etcdErr.Code()
+	}
+	switch code {
+	case grpccodes.Unavailable, grpccodes.DeadlineExceeded, grpccodes.ResourceExhausted, grpccodes.Aborted:
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
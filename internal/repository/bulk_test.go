@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSaveConfigSchemasBestEffort(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	mustSave(t, repo, "org/ns/b/v1.0.0")
+	// In key order: a fails once and is retried, b conflicts before its
+	// txn, c never reaches etcd and d is denied for good.
+	fake.failNext("Txn",
+		status.Error(codes.ResourceExhausted, "injected overload"),
+		nil,
+		status.Error(codes.PermissionDenied, "injected denial"),
+	)
+
+	result, err := repo.SaveConfigSchemasBestEffort(context.Background(), map[string]string{
+		"org/ns/a/v1.0.0": testSchema,
+		"org/ns/b/v1.0.0": testSchema,
+		"org/ns/c/v1.0.0": "properties: [unclosed",
+		"org/ns/d/v1.0.0": testSchema,
+	})
+	if err != nil {
+		t.Fatalf("SaveConfigSchemasBestEffort: %v", err)
+	}
+	if !slices.Equal(result.Succeeded, []string{"org/ns/a/v1.0.0"}) {
+		t.Errorf("succeeded: got %v", result.Succeeded)
+	}
+	if !slices.Equal(result.Conflicted, []string{"org/ns/b/v1.0.0"}) {
+		t.Errorf("conflicted: got %v", result.Conflicted)
+	}
+	if len(result.Failed) != 2 || !errors.Is(result.Failed["org/ns/c/v1.0.0"], ErrInvalidSchema) || status.Code(result.Failed["org/ns/d/v1.0.0"]) != codes.PermissionDenied {
+		t.Errorf("failed: got %v", result.Failed)
+	}
+	if fake.get("org/ns/a/v1.0.0") == nil {
+		t.Errorf("the retried entry was not stored")
+	}
+	if got := fake.callCount("Txn"); got != 4 {
+		t.Errorf("got %d txns, want 4", got)
+	}
+}
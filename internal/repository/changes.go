@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ChangedSince returns the schemas under prefix created or updated after
+// sinceRev, together with the revision to pass as sinceRev on the next
+// call. Deletions are not reported; use WatchSchemas to observe them.
+func (repo *EtcdRepository) ChangedSince(ctx context.Context, prefix string, sinceRev int64) ([]*pb.ConfigSchema, int64, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.ChangedSince")
+	defer span.End()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithMinModRev(sinceRev+1))
+	if err != nil {
+		return nil, 0, err
+	}
+	schemas := make([]*pb.ConfigSchema, 0, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		if repo.isReservedKey(string(kv.Key)) {
+			continue
+		}
+		schema, err := repo.decodeConfigSchema(kv)
+		if err != nil {
+			return nil, 0, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, res.Header.Revision, nil
+}
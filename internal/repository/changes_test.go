@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestChangedSince(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	changedKeys := func(sinceRev int64) ([]string, int64) {
+		t.Helper()
+		schemas, cursor, err := repo.ChangedSince(ctx, "org/ns/", sinceRev)
+		if err != nil {
+			t.Fatalf("ChangedSince(%d): %v", sinceRev, err)
+		}
+		keys := []string{}
+		for _, schema := range schemas {
+			details := schema.GetSchemaDetails()
+			keys = append(keys, details.GetSchemaName()+"/"+details.GetVersion())
+		}
+		return keys, cursor
+	}
+
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0")
+	keys, cursor := changedKeys(0)
+	if want := []string{"name/v1.0.0", "name/v1.1.0"}; !slices.Equal(keys, want) {
+		t.Errorf("initial pull: got %v, want %v", keys, want)
+	}
+
+	mustSave(t, repo, "org/ns/other/v1.0.0")
+	if err := repo.PatchConfigSchema(ctx, "org/ns/name/v1.0.0", []byte(`{"description":"patched"}`)); err != nil {
+		t.Fatalf("PatchConfigSchema: %v", err)
+	}
+	mustSave(t, repo, "elsewhere/ns/name/v1.0.0")
+	keys, next := changedKeys(cursor)
+	if want := []string{"name/v1.0.0", "other/v1.0.0"}; !slices.Equal(keys, want) {
+		t.Errorf("incremental pull: got %v, want %v", keys, want)
+	}
+	if next <= cursor {
+		t.Errorf("the cursor did not advance: %d after %d", next, cursor)
+	}
+
+	if keys, _ := changedKeys(next); len(keys) != 0 {
+		t.Errorf("nothing changed, got %v", keys)
+	}
+}
@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/namespace"
+)
+
+func (repo *EtcdRepository) etcdClient() *clientv3.Client {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return repo.client
+}
+
+func isAuthExpired(err error) bool {
+	return errors.Is(err, rpctypes.ErrInvalidAuthToken) || errors.Is(err, rpctypes.ErrAuthOldRevision)
+}
+
+// withReauth runs op and, if etcd rejected the auth token as expired or
+// stale, recreates the client (which authenticates anew) and runs op once
+// more. A second auth failure is returned as is, so bad credentials never
+// cause a retry loop.
+func (repo *EtcdRepository) withReauth(ctx context.Context, op func(cli *clientv3.Client) error) (err error) {
+	defer func() {
+		repo.recordDeadlineExceeded(ctx, err)
+	}()
+	cli, release := repo.acquireClient()
+	err = op(cli)
+	release()
+	if !isAuthExpired(err) {
+		return err
+	}
+	repo.logger.WarnContext(ctx, "etcd auth token expired, reconnecting", "error", err)
+	if err := repo.reconnect(cli); err != nil {
+		return err
+	}
+	cli, release = repo.acquireClient()
+	defer release()
+	return op(cli)
+}
+
+// acquireClient returns the current client and counts a call on it until
+// release is called, so reconnect does not close it under the call.
+func (repo *EtcdRepository) acquireClient() (cli *clientv3.Client, release func()) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	cli = repo.client
+	repo.clientCalls[cli]++
+	return cli, func() {
+		repo.mu.Lock()
+		repo.clientCalls[cli]--
+		retired := repo.clientCalls[cli] == 0 && cli != repo.client
+		if repo.clientCalls[cli] == 0 {
+			delete(repo.clientCalls, cli)
+		}
+		repo.mu.Unlock()
+		if retired {
+			cli.Close()
+		}
+	}
+}
+
+// reconnect replaces stale with a new client unless another caller already
+// has. The stale client is closed once its last call in flight returns.
+func (repo *EtcdRepository) reconnect(stale *clientv3.Client) error {
+	repo.mu.Lock()
+	if repo.client != stale {
+		repo.mu.Unlock()
+		return nil
+	}
+	cli, err := clientv3.New(repo.config)
+	if err != nil {
+		repo.mu.Unlock()
+		return err
+	}
+	repo.client = cli
+	idle := repo.clientCalls[stale] == 0
+	repo.mu.Unlock()
+	if idle {
+		stale.Close()
+	}
+	return nil
+}
+
+// namespacedKV scopes cli to the configured base prefix, so keys are
+// relative to it and nothing outside it can be read or written.
+func (repo *EtcdRepository) namespacedKV(cli *clientv3.Client) clientv3.KV {
+	if repo.basePrefix == "" {
+		return cli.KV
+	}
+	return namespace.NewKV(cli.KV, repo.basePrefix)
+}
+
+func (repo *EtcdRepository) namespacedWatcher(cli *clientv3.Client) clientv3.Watcher {
+	if repo.basePrefix == "" {
+		return cli.Watcher
+	}
+	return namespace.NewWatcher(cli.Watcher, repo.basePrefix)
+}
+
+type reauthKV struct {
+	repo *EtcdRepository
+}
+
+func (kv *reauthKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (res *clientv3.GetResponse, err error) {
+	if err := kv.repo.checkTenant(ctx, key); err != nil {
+		return nil, err
+	}
+	if kv.repo.freshness != nil {
+		return kv.boundedStaleGet(ctx, key, opts)
+	}
+	return kv.get(ctx, key, opts...)
+}
+
+func (kv *reauthKV) get(ctx context.Context, key string, opts ...clientv3.OpOption) (res *clientv3.GetResponse, err error) {
+	err = kv.repo.withReauth(ctx, func(cli *clientv3.Client) error {
+		res, err = kv.repo.namespacedKV(cli).Get(ctx, key, opts...)
+		return err
+	})
+	return res, err
+}
+
+func (kv *reauthKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (res *clientv3.PutResponse, err error) {
+	if kv.repo.readOnly {
+		return nil, ErrReadOnly
+	}
+	if err := kv.repo.checkTenant(ctx, key); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	err = kv.repo.withReauth(ctx, func(cli *clientv3.Client) error {
+		res, err = kv.repo.namespacedKV(cli).Put(ctx, key, val, opts...)
+		return err
+	})
+	if err == nil {
+		kv.repo.observeRevision(res.Header, start)
+	}
+	return res, err
+}
+
+func (kv *reauthKV) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (res *clientv3.DeleteResponse, err error) {
+	if kv.repo.readOnly {
+		return nil, ErrReadOnly
+	}
+	if err := kv.repo.checkTenant(ctx, key); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	err = kv.repo.withReauth(ctx, func(cli *clientv3.Client) error {
+		res, err = kv.repo.namespacedKV(cli).Delete(ctx, key, opts...)
+		return err
+	})
+	if err == nil {
+		kv.repo.observeRevision(res.Header, start)
+	}
+	return res, err
+}
+
+func (kv *reauthKV) Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (res *clientv3.CompactResponse, err error) {
+	err = kv.repo.withReauth(ctx, func(cli *clientv3.Client) error {
+		res, err = kv.repo.namespacedKV(cli).Compact(ctx, rev, opts...)
+		return err
+	})
+	return res, err
+}
+
+func (kv *reauthKV) Do(ctx context.Context, op clientv3.Op) (res clientv3.OpResponse, err error) {
+	if kv.repo.readOnly && !op.IsGet() {
+		return res, ErrReadOnly
+	}
+	if err := kv.repo.checkTenantTxn(ctx, nil, []clientv3.Op{op}); err != nil {
+		return res, err
+	}
+	err = kv.repo.withReauth(ctx, func(cli *clientv3.Client) error {
+		res, err = kv.repo.namespacedKV(cli).Do(ctx, op)
+		return err
+	})
+	return res, err
+}
+
+func (kv *reauthKV) Txn(ctx context.Context) clientv3.Txn {
+	return &reauthTxn{repo: kv.repo, ctx: ctx}
+}
+
+// reauthTxn records the transaction so it can be rebuilt on a fresh client
+// if the commit has to be retried.
+type reauthTxn struct {
+	repo  *EtcdRepository
+	ctx   context.Context
+	cmps  []clientv3.Cmp
+	thens []clientv3.Op
+	elses []clientv3.Op
+}
+
+func (txn *reauthTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	txn.cmps = append(txn.cmps, cs...)
+	return txn
+}
+
+func (txn *reauthTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	txn.thens = append(txn.thens, ops...)
+	return txn
+}
+
+func (txn *reauthTxn) Else(ops ...clientv3.Op) clientv3.Txn {
+	txn.elses = append(txn.elses, ops...)
+	return txn
+}
+
+func (txn *reauthTxn) Commit() (res *clientv3.TxnResponse, err error) {
+	if txn.repo.readOnly && !(isReadOnlyTxn(txn.thens) && isReadOnlyTxn(txn.elses)) {
+		return nil, ErrReadOnly
+	}
+	if err := txn.repo.checkTenantTxn(txn.ctx, txn.cmps, txn.thens, txn.elses); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	err = txn.repo.withReauth(txn.ctx, func(cli *clientv3.Client) error {
+		res, err = txn.repo.namespacedKV(cli).Txn(txn.ctx).If(txn.cmps...).Then(txn.thens...).Else(txn.elses...).Commit()
+		return err
+	})
+	if err == nil {
+		txn.repo.observeRevision(res.Header, start)
+	}
+	return res, err
+}
+
+func isReadOnlyTxn(ops []clientv3.Op) bool {
+	for _, op := range ops {
+		if op.IsTxn() {
+			_, thens, elses := op.Txn()
+			if !isReadOnlyTxn(thens) || !isReadOnlyTxn(elses) {
+				return false
+			}
+		} else if !op.IsGet() {
+			return false
+		}
+	}
+	return true
+}
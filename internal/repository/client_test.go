@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+)
+
+// The etcd client already retries auth failures it can refresh a token
+// for; withReauth handles those that reach the repository, so these tests
+// drive it directly with an operation that fails as configured.
+func TestReauthenticatesOnceAfterTokenExpiry(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	stale := repo.etcdClient()
+
+	var clients []*clientv3.Client
+	err := repo.withReauth(context.Background(), func(cli *clientv3.Client) error {
+		clients = append(clients, cli)
+		if len(clients) == 1 {
+			return rpctypes.ErrInvalidAuthToken
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withReauth: %v", err)
+	}
+	if len(clients) != 2 || clients[0] != stale || clients[1] == stale {
+		t.Errorf("want one attempt on the stale client and one on a new one, got %d attempts", len(clients))
+	}
+	if repo.etcdClient() == stale {
+		t.Errorf("the client was not replaced")
+	}
+	if stale.Ctx().Err() == nil {
+		t.Errorf("the idle stale client was left open")
+	}
+	if _, err := repo.SchemaExists(context.Background(), "org/ns/name/v1.0.0"); err != nil {
+		t.Errorf("the new client does not work: %v", err)
+	}
+}
+
+func TestReauthGivesUpAfterSecondAuthFailure(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	attempts := 0
+	err := repo.withReauth(context.Background(), func(cli *clientv3.Client) error {
+		attempts++
+		return rpctypes.ErrAuthOldRevision
+	})
+	if !errors.Is(err, rpctypes.ErrAuthOldRevision) {
+		t.Errorf("got %v, want the auth error", err)
+	}
+	if attempts != 2 {
+		t.Errorf("made %d attempts, want 2", attempts)
+	}
+}
+
+func TestReauthIgnoresOtherErrors(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	stale := repo.etcdClient()
+
+	attempts := 0
+	err := repo.withReauth(context.Background(), func(cli *clientv3.Client) error {
+		attempts++
+		return rpctypes.ErrPermissionDenied
+	})
+	if !errors.Is(err, rpctypes.ErrPermissionDenied) || attempts != 1 {
+		t.Errorf("got %v after %d attempts, want the error after 1", err, attempts)
+	}
+	if repo.etcdClient() != stale {
+		t.Errorf("the client was replaced")
+	}
+}
+
+func TestReconnectWaitsForCallsOnTheStaleClient(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	stale := repo.etcdClient()
+
+	// A save is in flight on the current client when another caller's
+	// token expires and it reconnects.
+	arrived, release := fake.hold("Txn")
+	saved := make(chan error, 1)
+	go func() { saved <- repo.SaveConfigSchema(context.Background(), "org/ns/name/v1.0.0", testSchema) }()
+	<-arrived
+
+	attempts := 0
+	err := repo.withReauth(context.Background(), func(cli *clientv3.Client) error {
+		attempts++
+		if attempts == 1 {
+			return rpctypes.ErrInvalidAuthToken
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("withReauth: got %v after %d attempts", err, attempts)
+	}
+	if repo.etcdClient() == stale {
+		t.Fatalf("the client was not replaced")
+	}
+	if stale.Ctx().Err() != nil {
+		t.Fatalf("the stale client was closed while a call was in flight on it")
+	}
+
+	release()
+	if err := <-saved; err != nil {
+		t.Errorf("the save in flight failed: %v", err)
+	}
+	if fake.get("org/ns/name/v1.0.0") == nil {
+		t.Errorf("the save in flight was not written")
+	}
+	if stale.Ctx().Err() == nil {
+		t.Errorf("the stale client was left open after its last call")
+	}
+	if _, err := repo.SchemaExists(context.Background(), "org/ns/name/v1.0.0"); err != nil {
+		t.Errorf("the new client does not work: %v", err)
+	}
+}
+
+func TestAutoSyncInterval(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	if interval := repo.config.AutoSyncInterval; interval != 0 {
+		t.Errorf("auto sync is on by default every %v", interval)
+	}
+	repo, fake := newTestRepo(t, WithAutoSyncInterval(30*time.Second))
+	if interval := repo.config.AutoSyncInterval; interval != 30*time.Second {
+		t.Errorf("got interval %v, want 30s", interval)
+	}
+	// A sync adopts the client URLs the members advertise.
+	if err := repo.etcdClient().Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if endpoints := repo.etcdClient().Endpoints(); len(endpoints) != 1 || endpoints[0] != "http://"+fake.addr {
+		t.Errorf("got endpoints %v", endpoints)
+	}
+}
+
+func TestDialOptionsReachTheClient(t *testing.T) {
+	var calls atomic.Int64
+	interceptor := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		calls.Add(1)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	repo, _ := newTestRepo(t, WithDialOptions(grpc.WithUnaryInterceptor(interceptor)), WithKeepAlive(20*time.Second, 5*time.Second))
+	if len(repo.config.DialOptions) == 0 {
+		t.Fatalf("no dial options in the client config")
+	}
+	if repo.config.DialKeepAliveTime != 20*time.Second || repo.config.DialKeepAliveTimeout != 5*time.Second {
+		t.Errorf("keepalive: got %v and %v", repo.config.DialKeepAliveTime, repo.config.DialKeepAliveTimeout)
+	}
+
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	if calls.Load() == 0 {
+		t.Errorf("the interceptor saw no calls")
+	}
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+)
+
+// coalescedSave saves schema under key. With WithWriteCoalescing,
+// concurrent identical saves share one execution whose error is handed to
+// every caller. The shared execution is detached from the cancellation of
+// the caller that started it, so no single caller decides the outcome for
+// the others; it still carries that caller's context values and is bounded
+// by the operation timeout. Each caller waits only as long as its own ctx
+// allows and gets ctx.Err() when that ends first.
+func (repo *EtcdRepository) coalescedSave(ctx context.Context, key, schema string, options *saveOptions) error {
+	if repo.writes == nil {
+		return repo.saveConfigSchema(ctx, key, schema, options)
+	}
+	// A caller must not share in a save its tenant could not make.
+	if err := repo.checkTenant(ctx, key); err != nil {
+		return err
+	}
+	shared := context.WithoutCancel(ctx)
+	results := repo.writes.DoChan(options.coalescingKey(key, schema), func() (interface{}, error) {
+		return nil, repo.saveConfigSchema(shared, key, schema, options)
+	})
+	select {
+	case res := <-results:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
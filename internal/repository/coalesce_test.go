@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitingContext reports through waiting when a caller first waits on it,
+// which coalescedSave only does after joining or starting a save.
+type waitingContext struct {
+	context.Context
+	once    sync.Once
+	waiting chan struct{}
+}
+
+func newWaitingContext(parent context.Context) *waitingContext {
+	return &waitingContext{Context: parent, waiting: make(chan struct{})}
+}
+
+func (ctx *waitingContext) Done() <-chan struct{} {
+	ctx.once.Do(func() { close(ctx.waiting) })
+	return ctx.Context.Done()
+}
+
+func TestWriteCoalescingCollapsesConcurrentSaves(t *testing.T) {
+	repo, fake := newTestRepo(t, WithWriteCoalescing())
+	arrived, release := fake.hold("Txn")
+	key := "org/ns/name/v1.0.0"
+
+	const savers = 10
+	errs := make(chan error, savers)
+	go func() { errs <- repo.SaveConfigSchema(context.Background(), key, testSchema) }()
+	<-arrived
+	for range savers - 1 {
+		ctx := newWaitingContext(context.Background())
+		go func() { errs <- repo.SaveConfigSchema(ctx, key, testSchema) }()
+		<-ctx.waiting
+	}
+	release()
+	for range savers {
+		if err := <-errs; err != nil {
+			t.Errorf("save: %v", err)
+		}
+	}
+	if txns := fake.callCount("Txn"); txns != 1 {
+		t.Errorf("%d transactions reached etcd, want 1", txns)
+	}
+}
+
+func TestWriteCoalescingDoesNotShareDifferentSaves(t *testing.T) {
+	repo, fake := newTestRepo(t, WithWriteCoalescing())
+	arrived, release := fake.hold("Txn")
+
+	errs := make(chan error, 2)
+	go func() { errs <- repo.SaveConfigSchema(context.Background(), "org/ns/name/v1.0.0", testSchema) }()
+	<-arrived
+	go func() {
+		errs <- repo.SaveConfigSchema(context.Background(), "org/ns/name/v1.0.0", testSchema, WithAuthor("someone"))
+	}()
+	// The second save checks the key itself instead of joining the first.
+	for fake.callCount("Range") < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	release()
+	var exists *SchemaExistsError
+	if err1, err2 := <-errs, <-errs; !(err1 == nil && errors.As(err2, &exists)) && !(err2 == nil && errors.As(err1, &exists)) {
+		t.Errorf("got %v and %v, want one save to win and the other to find the key taken", err1, err2)
+	}
+}
+
+func TestWriteCoalescingWaiterHonorsItsContext(t *testing.T) {
+	repo, fake := newTestRepo(t, WithWriteCoalescing())
+	arrived, release := fake.hold("Txn")
+	key := "org/ns/name/v1.0.0"
+
+	leader := make(chan error, 1)
+	go func() { leader <- repo.SaveConfigSchema(context.Background(), key, testSchema) }()
+	<-arrived
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiter := newWaitingContext(ctx)
+	follower := make(chan error, 1)
+	go func() { follower <- repo.SaveConfigSchema(waiter, key, testSchema) }()
+	<-waiter.waiting
+	cancel()
+	if err := <-follower; !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled waiter: got %v, want context.Canceled", err)
+	}
+
+	release()
+	if err := <-leader; err != nil {
+		t.Errorf("the shared save was affected by the canceled waiter: %v", err)
+	}
+	if fake.get(key) == nil {
+		t.Errorf("the shared save was not written")
+	}
+}
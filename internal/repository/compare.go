@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// VersionComparator orders the versions of a schema, deciding how listings
+// are sorted and which version is the latest. Compare returns -1, 0 or +1.
+type VersionComparator interface {
+	Compare(a, b string) int
+}
+
+type semverComparator struct{}
+
+func (semverComparator) Compare(a, b string) int {
+	return semver.Compare(a, b)
+}
+
+// LexicalComparator orders versions as plain strings, which suits
+// zero-padded date-based versions such as "2024.01.15".
+var LexicalComparator VersionComparator = lexicalComparator{}
+
+type lexicalComparator struct{}
+
+func (lexicalComparator) Compare(a, b string) int {
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestLexicalComparatorOrdersDateVersions(t *testing.T) {
+	repo, _ := newTestRepo(t, WithVersionComparator(LexicalComparator))
+	ctx := context.Background()
+	saveVersions(t, repo, "org/ns/name/", "2024.01.15", "2023.12.31", "2024.02.01")
+
+	schemas, err := repo.GetSchemasByPrefix(ctx, "org/ns/name/")
+	if err != nil {
+		t.Fatalf("GetSchemasByPrefix: %v", err)
+	}
+	if got, want := schemaVersions(schemas), []string{"2023.12.31", "2024.01.15", "2024.02.01"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	latest, err := repo.GetLatestConfigSchema(ctx, "org", "ns", "name")
+	if err != nil || latest.GetSchemaDetails().GetVersion() != "2024.02.01" {
+		t.Errorf("got latest %v, %v, want 2024.02.01", latest.GetSchemaDetails(), err)
+	}
+	if version, err := repo.GetLatestVersionByPrefix(ctx, "org/ns/name/"); err != nil || version != "2024.02.01" {
+		t.Errorf("GetLatestVersionByPrefix: got %q, %v", version, err)
+	}
+}
+
+func TestComparators(t *testing.T) {
+	for _, test := range []struct {
+		comparator VersionComparator
+		a, b       string
+		want       int
+	}{
+		{semverComparator{}, "v1.10.0", "v1.9.0", 1},
+		{semverComparator{}, "v1.0.0", "v1.0.0+build", 0},
+		{LexicalComparator, "v1.10.0", "v1.9.0", -1},
+		{LexicalComparator, "2024.01.15", "2024.01.15", 0},
+	} {
+		if got := test.comparator.Compare(test.a, test.b); got != test.want {
+			t.Errorf("%T: Compare(%s, %s) = %d, want %d", test.comparator, test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	"golang.org/x/mod/semver"
+)
+
+// CheckCompatibility reports whether consumerVersion, a SemVer string, is
+// at least the minimum consumer version stored with the schema under key.
+// Schemas saved without a minimum are compatible with every consumer.
+func (repo *EtcdRepository) CheckCompatibility(ctx context.Context, key, consumerVersion string) (bool, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.CheckCompatibility")
+	defer span.End()
+
+	if !semver.IsValid(consumerVersion) {
+		return false, fmt.Errorf("consumer version '%s' is not a valid SemVer string", consumerVersion)
+	}
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if len(res.Kvs) == 0 {
+		return false, &SchemaNotFoundError{Key: key}
+	}
+	var schemaData pb.ConfigSchemaData
+	if err := repo.decodeSchemaData(res.Kvs[0].Value, &schemaData); err != nil {
+		return false, err
+	}
+	minimum := schemaData.GetMinConsumerVersion()
+	return minimum == "" || semver.Compare(consumerVersion, minimum) >= 0, nil
+}
+
+// Incompatibility is a breaking change found by CheckBackwardCompatible.
+// Path is a JSON pointer into the new schema.
+type Incompatibility struct {
+	Path   string
+	Reason string
+}
+
+// CheckBackwardCompatible compares two versions of a JSON Schema and lists
+// the changes that could reject configs the old version accepted: removed
+// required properties, newly required properties and narrowed types. Only
+// properties and items are followed; combinators and $ref are not resolved.
+// No incompatibilities means the new version is backward compatible.
+func (repo *EtcdRepository) CheckBackwardCompatible(ctx context.Context, org, namespace, name, oldVersion, newVersion string) ([]Incompatibility, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.CheckBackwardCompatible")
+	defer span.End()
+
+	var documents [2]map[string]interface{}
+	for i, version := range []string{oldVersion, newVersion} {
+		schemaJson, err := repo.getSchemaJSON(ctx, repo.getSchemaKey(org, namespace, name, version))
+		if err != nil {
+			return nil, err
+		}
+		if err := decodeJSON(schemaJson, &documents[i]); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+		}
+	}
+	return compareSchemas("#", documents[0], documents[1]), nil
+}
+
+func compareSchemas(path string, before, after map[string]interface{}) []Incompatibility {
+	var found []Incompatibility
+	oldTypes, newTypes := schemaTypes(before), schemaTypes(after)
+	for _, oldType := range oldTypes {
+		if len(newTypes) > 0 && !slices.Contains(newTypes, oldType) && !(oldType == "integer" && slices.Contains(newTypes, "number")) {
+			found = append(found, Incompatibility{Path: path, Reason: fmt.Sprintf("type narrowed from %v to %v", oldTypes, newTypes)})
+			break
+		}
+	}
+	if len(oldTypes) == 0 && len(newTypes) > 0 {
+		found = append(found, Incompatibility{Path: path, Reason: fmt.Sprintf("type restricted to %v", newTypes)})
+	}
+
+	oldRequired, newRequired := stringSet(before["required"]), stringSet(after["required"])
+	oldProperties, _ := before["properties"].(map[string]interface{})
+	newProperties, _ := after["properties"].(map[string]interface{})
+	for _, property := range sortedKeys(oldProperties) {
+		propertyPath := path + "/properties/" + property
+		newProperty, exists := newProperties[property]
+		if !exists {
+			if oldRequired[property] {
+				found = append(found, Incompatibility{Path: propertyPath, Reason: "required property removed"})
+			}
+			continue
+		}
+		oldSchema, oldOk := oldProperties[property].(map[string]interface{})
+		newSchema, newOk := newProperty.(map[string]interface{})
+		if oldOk && newOk {
+			found = append(found, compareSchemas(propertyPath, oldSchema, newSchema)...)
+		}
+	}
+	for _, property := range sortedKeys(newRequired) {
+		if !oldRequired[property] {
+			found = append(found, Incompatibility{Path: path + "/properties/" + property, Reason: "property became required"})
+		}
+	}
+
+	oldItems, oldOk := before["items"].(map[string]interface{})
+	newItems, newOk := after["items"].(map[string]interface{})
+	if oldOk && newOk {
+		found = append(found, compareSchemas(path+"/items", oldItems, newItems)...)
+	}
+	return found
+}
+
+// schemaTypes returns the types a schema's "type" keyword allows; none
+// means any type.
+func schemaTypes(schema map[string]interface{}) []string {
+	switch value := schema["type"].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		types := make([]string, 0, len(value))
+		for _, item := range value {
+			if name, ok := item.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func stringSet(value interface{}) map[string]bool {
+	items, _ := value.([]interface{})
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		if name, ok := item.(string); ok {
+			set[name] = true
+		}
+	}
+	return set
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
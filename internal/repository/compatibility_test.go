@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	key := "org/ns/name/v1.0.0"
+	mustSave(t, repo, key, WithMinConsumerVersion("v2.3.0"))
+	mustSave(t, repo, "org/ns/name/v1.1.0")
+
+	schemaData, err := repo.GetConfigSchema(ctx, key)
+	if err != nil || schemaData.GetMinConsumerVersion() != "v2.3.0" {
+		t.Errorf("stored minimum: got %q, %v", schemaData.GetMinConsumerVersion(), err)
+	}
+	for consumer, want := range map[string]bool{
+		"v2.3.0":      true,
+		"v2.10.0":     true,
+		"v3.0.0":      true,
+		"v2.2.9":      false,
+		"v2.3.0-rc.1": false,
+		"v1.99.0":     false,
+	} {
+		if compatible, err := repo.CheckCompatibility(ctx, key, consumer); err != nil || compatible != want {
+			t.Errorf("%s: got %t, %v, want %t", consumer, compatible, err, want)
+		}
+	}
+	if compatible, err := repo.CheckCompatibility(ctx, "org/ns/name/v1.1.0", "v0.0.1"); err != nil || !compatible {
+		t.Errorf("no minimum: got %t, %v, want compatible", compatible, err)
+	}
+
+	if _, err := repo.CheckCompatibility(ctx, key, "2.3"); err == nil {
+		t.Errorf("accepted a consumer version that is not SemVer")
+	}
+	if _, err := repo.CheckCompatibility(ctx, "org/ns/name/v9.0.0", "v1.0.0"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing: got %v, want ErrSchemaNotFound", err)
+	}
+	if err := repo.SaveConfigSchema(ctx, "org/ns/name/v2.0.0", testSchema, WithMinConsumerVersion("latest")); err == nil {
+		t.Errorf("saved a minimum that is not SemVer")
+	}
+}
+
+func TestCheckBackwardCompatible(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	schemas := map[string]string{
+		"v1.0.0": `
+type: object
+required: [name, port]
+properties:
+  name: {type: string}
+  port: {type: integer}
+  tags: {type: array, items: {type: string}}
+`,
+		// Adds an optional property and widens port.
+		"v1.1.0": `
+type: object
+required: [name, port]
+properties:
+  name: {type: string}
+  port: {type: number}
+  tags: {type: array, items: {type: [string, "null"]}}
+  debug: {type: boolean}
+`,
+		// Drops a required property, narrows two types and requires a new one.
+		"v2.0.0": `
+type: object
+required: [name, region]
+properties:
+  name: {type: integer}
+  tags: {type: array, items: {type: integer}}
+  region: {type: string}
+`,
+	}
+	for version, schema := range schemas {
+		if err := repo.SaveConfigSchema(ctx, "org/ns/name/"+version, schema); err != nil {
+			t.Fatalf("SaveConfigSchema(%s): %v", version, err)
+		}
+	}
+
+	found, err := repo.CheckBackwardCompatible(ctx, "org", "ns", "name", "v1.0.0", "v1.1.0")
+	if err != nil || len(found) != 0 {
+		t.Errorf("additive change: got %v, %v, want compatible", found, err)
+	}
+
+	found, err = repo.CheckBackwardCompatible(ctx, "org", "ns", "name", "v1.0.0", "v2.0.0")
+	if err != nil {
+		t.Fatalf("CheckBackwardCompatible: %v", err)
+	}
+	want := []Incompatibility{
+		{Path: "#/properties/name", Reason: "type narrowed from [string] to [integer]"},
+		{Path: "#/properties/port", Reason: "required property removed"},
+		{Path: "#/properties/tags/items", Reason: "type narrowed from [string] to [integer]"},
+		{Path: "#/properties/region", Reason: "property became required"},
+	}
+	if !slices.Equal(found, want) {
+		t.Errorf("breaking change:\n got %v\nwant %v", found, want)
+	}
+
+	if _, err := repo.CheckBackwardCompatible(ctx, "org", "ns", "name", "v1.0.0", "v9.0.0"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing version: got %v, want ErrSchemaNotFound", err)
+	}
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// constraint is a disjunction ("||") of conjunctions of version bounds,
+// e.g. "^1.2.0 || >=2.1.0 <2.3.0".
+type constraint struct {
+	groups [][]versionBound
+}
+
+type versionBound struct {
+	op      string
+	version string
+}
+
+func parseConstraint(expr string) (*constraint, error) {
+	c := &constraint{}
+	for _, group := range strings.Split(expr, "||") {
+		bounds, err := parseConstraintGroup(group)
+		if err != nil {
+			return nil, fmt.Errorf("%w '%s': %v", ErrInvalidConstraint, expr, err)
+		}
+		c.groups = append(c.groups, bounds)
+	}
+	return c, nil
+}
+
+func (c *constraint) check(version string) bool {
+	if !semver.IsValid(version) {
+		return false
+	}
+	for _, group := range c.groups {
+		if groupMatches(group, version) {
+			return true
+		}
+	}
+	return false
+}
+
+func groupMatches(bounds []versionBound, version string) bool {
+	for _, bound := range bounds {
+		cmp := semver.Compare(version, bound.version)
+		var ok bool
+		switch bound.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func parseConstraintGroup(group string) ([]versionBound, error) {
+	group = strings.TrimSpace(group)
+	if from, to, found := strings.Cut(group, " - "); found {
+		lower, err := expandBound(">=", strings.TrimSpace(from))
+		if err != nil {
+			return nil, err
+		}
+		upper, err := expandBound("<=", strings.TrimSpace(to))
+		if err != nil {
+			return nil, err
+		}
+		return append(lower, upper...), nil
+	}
+
+	var terms []string
+	pending := ""
+	for _, field := range strings.FieldsFunc(group, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if strings.TrimLeft(field, "<>=!~^") == "" {
+			pending += field
+			continue
+		}
+		terms = append(terms, pending+field)
+		pending = ""
+	}
+	if pending != "" {
+		return nil, fmt.Errorf("operator '%s' without a version", pending)
+	}
+
+	var bounds []versionBound
+	for _, term := range terms {
+		op := term[:len(term)-len(strings.TrimLeft(term, "<>=!~^"))]
+		expanded, err := expandBound(op, term[len(op):])
+		if err != nil {
+			return nil, err
+		}
+		bounds = append(bounds, expanded...)
+	}
+	return bounds, nil
+}
+
+// expandBound turns a single operator and a possibly partial version
+// ("1", "1.2", "1.x") into one or two concrete semver bounds.
+func expandBound(op string, version string) ([]versionBound, error) {
+	version = strings.TrimPrefix(version, "v")
+	if version == "" || version == "*" || version == "x" || version == "X" {
+		if op == "" || op == "=" || op == ">=" || op == "^" || op == "~" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("operator '%s' requires a version", op)
+	}
+	if strings.ContainsAny(version, "-+") {
+		if !semver.IsValid("v" + version) {
+			return nil, fmt.Errorf("invalid version '%s'", version)
+		}
+		switch op {
+		case "", "=", "!=", ">", ">=", "<", "<=":
+			return []versionBound{{op: normalizeOp(op), version: "v" + version}}, nil
+		}
+		return nil, fmt.Errorf("operator '%s' cannot be used with a pre-release version", op)
+	}
+
+	var parts [3]int
+	n := 0
+	for _, part := range strings.Split(version, ".") {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		if n == 3 {
+			return nil, fmt.Errorf("invalid version '%s'", version)
+		}
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid version '%s'", version)
+		}
+		parts[n] = value
+		n++
+	}
+	if n == 0 {
+		return expandBound(op, "*")
+	}
+
+	lower := formatVersion(parts)
+	switch op {
+	case "", "=":
+		if n == 3 {
+			return []versionBound{{op: "=", version: lower}}, nil
+		}
+		return []versionBound{{op: ">=", version: lower}, {op: "<", version: bumpVersion(parts, n-1)}}, nil
+	case "!=":
+		if n != 3 {
+			return nil, fmt.Errorf("operator '!=' requires a full version")
+		}
+		return []versionBound{{op: "!=", version: lower}}, nil
+	case ">":
+		if n == 3 {
+			return []versionBound{{op: ">", version: lower}}, nil
+		}
+		return []versionBound{{op: ">=", version: bumpVersion(parts, n-1)}}, nil
+	case ">=":
+		return []versionBound{{op: ">=", version: lower}}, nil
+	case "<":
+		return []versionBound{{op: "<", version: lower}}, nil
+	case "<=":
+		if n == 3 {
+			return []versionBound{{op: "<=", version: lower}}, nil
+		}
+		return []versionBound{{op: "<", version: bumpVersion(parts, n-1)}}, nil
+	case "~":
+		if n == 1 {
+			return []versionBound{{op: ">=", version: lower}, {op: "<", version: bumpVersion(parts, 0)}}, nil
+		}
+		return []versionBound{{op: ">=", version: lower}, {op: "<", version: bumpVersion(parts, 1)}}, nil
+	case "^":
+		switch {
+		case parts[0] > 0 || n == 1:
+			return []versionBound{{op: ">=", version: lower}, {op: "<", version: bumpVersion(parts, 0)}}, nil
+		case parts[1] > 0 || n == 2:
+			return []versionBound{{op: ">=", version: lower}, {op: "<", version: bumpVersion(parts, 1)}}, nil
+		default:
+			return []versionBound{{op: ">=", version: lower}, {op: "<", version: bumpVersion(parts, 2)}}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operator '%s'", op)
+}
+
+func normalizeOp(op string) string {
+	if op == "" {
+		return "="
+	}
+	return op
+}
+
+func bumpVersion(parts [3]int, index int) string {
+	bumped := parts
+	bumped[index]++
+	for i := index + 1; i < len(bumped); i++ {
+		bumped[i] = 0
+	}
+	return formatVersion(bumped)
+}
+
+func formatVersion(parts [3]int) string {
+	return fmt.Sprintf("v%d.%d.%d", parts[0], parts[1], parts[2])
+}
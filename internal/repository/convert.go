@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	yaml "sigs.k8s.io/yaml/goyaml.v3"
+)
+
+// YAMLConverter converts schema bodies between the YAML accepted and
+// returned by the repository and the JSON it stores.
+type YAMLConverter interface {
+	YAMLToJSON(data []byte) ([]byte, error)
+	JSONToYAML(data []byte) ([]byte, error)
+}
+
+// defaultYAMLConverter carries numbers between YAML and JSON as their
+// literal text, so large integers and high-precision decimals are never
+// rounded through float64.
+type defaultYAMLConverter struct{}
+
+var jsonNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+func (defaultYAMLConverter) YAMLToJSON(data []byte) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	value, err := (&yamlDecoder{visiting: make(map[*yaml.Node]bool)}).decode(&document)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+func (defaultYAMLConverter) JSONToYAML(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := decodeJSON(data, &value); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(jsonToYAMLNode(value)); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// maxYAMLAliasNodes bounds how many nodes aliases may expand to in one
+// document, so a few bytes of nested aliases cannot blow up into an
+// enormous schema.
+const maxYAMLAliasNodes = 100000
+
+// yamlDecoder converts a YAML node tree to JSON values, expanding aliases.
+// visiting holds the anchored nodes being converted, so an alias to one of
+// them, which would expand forever, is rejected.
+type yamlDecoder struct {
+	visiting   map[*yaml.Node]bool
+	aliasDepth int
+	aliasNodes int
+}
+
+func (decoder *yamlDecoder) decode(node *yaml.Node) (interface{}, error) {
+	if decoder.aliasDepth > 0 {
+		decoder.aliasNodes++
+		if decoder.aliasNodes > maxYAMLAliasNodes {
+			return nil, fmt.Errorf("line %d: aliases expand to more than %d nodes", node.Line, maxYAMLAliasNodes)
+		}
+	}
+	if node.Anchor != "" {
+		decoder.visiting[node] = true
+		defer delete(decoder.visiting, node)
+	}
+	switch node.Kind {
+	case 0:
+		return nil, nil
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return decoder.decode(node.Content[0])
+	case yaml.AliasNode:
+		if decoder.visiting[node.Alias] {
+			return nil, fmt.Errorf("line %d: alias *%s refers to its own anchor", node.Line, node.Value)
+		}
+		decoder.aliasDepth++
+		defer func() { decoder.aliasDepth-- }()
+		return decoder.decode(node.Alias)
+	case yaml.SequenceNode:
+		values := make([]interface{}, len(node.Content))
+		for i, child := range node.Content {
+			value, err := decoder.decode(child)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	case yaml.MappingNode:
+		object := make(map[string]interface{}, len(node.Content)/2)
+		var merges []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, child := node.Content[i], node.Content[i+1]
+			if key.ShortTag() == "!!merge" {
+				merges = append(merges, child)
+				continue
+			}
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: unsupported non-scalar mapping key", key.Line)
+			}
+			value, err := decoder.decode(child)
+			if err != nil {
+				return nil, err
+			}
+			object[key.Value] = value
+		}
+		// Explicit keys take precedence over merged ones wherever they appear.
+		for _, merge := range merges {
+			if err := decoder.merge(object, merge); err != nil {
+				return nil, err
+			}
+		}
+		return object, nil
+	}
+
+	switch node.ShortTag() {
+	case "!!str", "!!timestamp":
+		return node.Value, nil
+	case "!!int", "!!float":
+		if jsonNumberPattern.MatchString(node.Value) {
+			return json.Number(node.Value), nil
+		}
+	}
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (decoder *yamlDecoder) merge(object map[string]interface{}, merge *yaml.Node) error {
+	if merge.Kind == yaml.SequenceNode {
+		for _, child := range merge.Content {
+			if err := decoder.merge(object, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	value, err := decoder.decode(merge)
+	if err != nil {
+		return err
+	}
+	merged, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("line %d: merge value is not a mapping", merge.Line)
+	}
+	for name, child := range merged {
+		if _, exists := object[name]; !exists {
+			object[name] = child
+		}
+	}
+	return nil
+}
+
+func jsonToYAMLNode(value interface{}) *yaml.Node {
+	switch current := value.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(current))
+		for name := range current {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, name := range names {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name},
+				jsonToYAMLNode(current[name]),
+			)
+		}
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, child := range current {
+			node.Content = append(node.Content, jsonToYAMLNode(child))
+		}
+		return node
+	case json.Number:
+		// Left untagged so the literal is written plain even when it is
+		// out of range for the YAML library's own number types.
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: current.String()}
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: current}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(current)}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestYAMLConverterKeepsNumberPrecision(t *testing.T) {
+	converter := defaultYAMLConverter{}
+	schema := "properties:\n  id:\n    const: 12345678901234567890123\n  ratio:\n    const: 3.14159265358979323846264338327950288\ntype: object\n"
+	schemaJson, err := converter.YAMLToJSON([]byte(schema))
+	if err != nil {
+		t.Fatalf("YAMLToJSON: %v", err)
+	}
+	for _, literal := range []string{"12345678901234567890123", "3.14159265358979323846264338327950288"} {
+		if !strings.Contains(string(schemaJson), literal) {
+			t.Errorf("JSON %s lost %s", schemaJson, literal)
+		}
+	}
+	schemaYaml, err := converter.JSONToYAML(schemaJson)
+	if err != nil {
+		t.Fatalf("JSONToYAML: %v", err)
+	}
+	if string(schemaYaml) != schema {
+		t.Errorf("round trip changed the schema:\ngot:\n%s\nwant:\n%s", schemaYaml, schema)
+	}
+}
+
+func TestYAMLConverterExpandsAliases(t *testing.T) {
+	schemaJson, err := defaultYAMLConverter{}.YAMLToJSON([]byte("definitions:\n  port: &port {type: integer}\nproperties:\n  a: *port\n  b:\n    <<: *port\n    minimum: 1\n"))
+	if err != nil {
+		t.Fatalf("YAMLToJSON: %v", err)
+	}
+	want := `{"definitions":{"port":{"type":"integer"}},"properties":{"a":{"type":"integer"},"b":{"minimum":1,"type":"integer"}}}`
+	if string(schemaJson) != want {
+		t.Errorf("got %s, want %s", schemaJson, want)
+	}
+}
+
+func TestYAMLConverterRejectsAliasCycles(t *testing.T) {
+	for _, schema := range []string{
+		"a: &a [*a]",
+		"a: &a {b: *a}",
+		"a: &a {<<: *a}",
+	} {
+		if _, err := (defaultYAMLConverter{}).YAMLToJSON([]byte(schema)); err == nil {
+			t.Errorf("%q: expected an error", schema)
+		}
+	}
+}
+
+func TestYAMLConverterRejectsAliasBombs(t *testing.T) {
+	bomb := `a: &a ["lol","lol","lol","lol","lol","lol","lol","lol","lol"]
+b: &b [*a,*a,*a,*a,*a,*a,*a,*a,*a]
+c: &c [*b,*b,*b,*b,*b,*b,*b,*b,*b]
+d: &d [*c,*c,*c,*c,*c,*c,*c,*c,*c]
+e: &e [*d,*d,*d,*d,*d,*d,*d,*d,*d]
+f: &f [*e,*e,*e,*e,*e,*e,*e,*e,*e]
+g: &g [*f,*f,*f,*f,*f,*f,*f,*f,*f]
+`
+	_, err := defaultYAMLConverter{}.YAMLToJSON([]byte(bomb))
+	if err == nil || !strings.Contains(err.Error(), "aliases expand") {
+		t.Fatalf("expected the alias expansion limit, got %v", err)
+	}
+}
+
+// countingConverter wraps the default converter and counts its calls.
+type countingConverter struct {
+	defaultYAMLConverter
+	toJSON, toYAML int
+}
+
+func (converter *countingConverter) YAMLToJSON(data []byte) ([]byte, error) {
+	converter.toJSON++
+	return converter.defaultYAMLConverter.YAMLToJSON(data)
+}
+
+func (converter *countingConverter) JSONToYAML(data []byte) ([]byte, error) {
+	converter.toYAML++
+	return converter.defaultYAMLConverter.JSONToYAML(data)
+}
+
+func TestRepositoryUsesYAMLConverter(t *testing.T) {
+	converter := &countingConverter{}
+	repo, _ := newTestRepo(t, WithYAMLConverter(converter))
+
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	if converter.toJSON != 1 {
+		t.Errorf("save: YAMLToJSON called %d times, want 1", converter.toJSON)
+	}
+	if _, err := repo.GetConfigSchema(context.Background(), "org/ns/name/v1.0.0"); err != nil {
+		t.Fatalf("GetConfigSchema: %v", err)
+	}
+	if converter.toYAML != 1 {
+		t.Errorf("get: JSONToYAML called %d times, want 1", converter.toYAML)
+	}
+}
+
+func TestSaveAndGetKeepNumberPrecision(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	schema := "properties:\n  id:\n    const: 9007199254740993123\n  ratio:\n    const: 0.1000000000000000055511151231257827\ntype: object\n"
+	if err := repo.SaveConfigSchema(ctx, "org/ns/name/v1.0.0", schema); err != nil {
+		t.Fatalf("SaveConfigSchema: %v", err)
+	}
+
+	schemaData, err := repo.GetConfigSchema(ctx, "org/ns/name/v1.0.0")
+	if err != nil {
+		t.Fatalf("GetConfigSchema: %v", err)
+	}
+	if schemaData.GetSchema() != schema {
+		t.Errorf("got:\n%s\nwant:\n%s", schemaData.GetSchema(), schema)
+	}
+	schemaJson, err := repo.GetConfigSchemaJSON(ctx, "org/ns/name/v1.0.0")
+	if err != nil {
+		t.Fatalf("GetConfigSchemaJSON: %v", err)
+	}
+	for _, literal := range []string{"9007199254740993123", "0.1000000000000000055511151231257827"} {
+		if !strings.Contains(string(schemaJson), literal) {
+			t.Errorf("JSON %s lost %s", schemaJson, literal)
+		}
+	}
+}
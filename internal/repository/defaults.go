@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+// ApplyDefaults fills the fields config omits with the "default" values
+// declared in the schema's properties, recursing into nested objects, and
+// returns the result as JSON. Fields present in config are never changed.
+func (repo *EtcdRepository) ApplyDefaults(ctx context.Context, key string, config []byte) ([]byte, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.ApplyDefaults")
+	defer span.End()
+
+	configJson, err := repo.converter.YAMLToJSON(config)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	var document interface{}
+	if err := decodeJSON(configJson, &document); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, &SchemaNotFoundError{Key: key}
+	}
+	var schemaData pb.ConfigSchemaData
+	if err := repo.decodeSchemaData(res.Kvs[0].Value, &schemaData); err != nil {
+		return nil, err
+	}
+	var schema interface{}
+	if err := decodeJSON([]byte(schemaData.GetSchema()), &schema); err != nil {
+		return nil, err
+	}
+	return json.Marshal(applyDefaults(schema, document))
+}
+
+func applyDefaults(schema interface{}, value interface{}) interface{} {
+	schemaObject, ok := schema.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	properties, ok := schemaObject["properties"].(map[string]interface{})
+	if !ok {
+		return value
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	for name, propertySchema := range properties {
+		if _, present := object[name]; !present {
+			propertyObject, ok := propertySchema.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			defaultValue, ok := propertyObject["default"]
+			if !ok {
+				continue
+			}
+			object[name] = deepCopyJSON(defaultValue)
+		}
+		object[name] = applyDefaults(propertySchema, object[name])
+	}
+	return object
+}
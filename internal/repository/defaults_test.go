@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+const defaultsSchema = `type: object
+properties:
+  port:
+    type: integer
+    default: 8080
+  tls:
+    type: object
+    default: {}
+    properties:
+      enabled:
+        type: boolean
+        default: false
+      ciphers:
+        type: array
+        default: [aes]
+`
+
+func TestApplyDefaults(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	key := "org/ns/name/v1.0.0"
+	if err := repo.SaveConfigSchema(context.Background(), key, defaultsSchema); err != nil {
+		t.Fatalf("SaveConfigSchema: %v", err)
+	}
+	for _, test := range []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"empty", `{}`, `{"port":8080,"tls":{"ciphers":["aes"],"enabled":false}}`},
+		{"nested", `{"tls":{"enabled":true}}`, `{"port":8080,"tls":{"ciphers":["aes"],"enabled":true}}`},
+		{"present", "port: 9090\ntls:\n  ciphers: []\n  enabled: true\n", `{"port":9090,"tls":{"ciphers":[],"enabled":true}}`},
+		{"extra", `{"host":"db","port":null}`, `{"host":"db","port":null,"tls":{"ciphers":["aes"],"enabled":false}}`},
+	} {
+		got, err := repo.ApplyDefaults(context.Background(), key, []byte(test.config))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if string(got) != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+
+	if _, err := repo.ApplyDefaults(context.Background(), "org/ns/name/v2.0.0", []byte(`{}`)); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing schema: got %v, want ErrSchemaNotFound", err)
+	}
+	if _, err := repo.ApplyDefaults(context.Background(), key, []byte("port: [")); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("bad config: got %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestApplyDefaultsCopiesDefaults(t *testing.T) {
+	schema := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"tags": map[string]interface{}{"default": []interface{}{"a"}},
+		},
+	}
+	first := applyDefaults(schema, map[string]interface{}{}).(map[string]interface{})
+	first["tags"].([]interface{})[0] = "changed"
+	second := applyDefaults(schema, map[string]interface{}{}).(map[string]interface{})
+	if tag := second["tags"].([]interface{})[0]; tag != "a" {
+		t.Errorf("a filled default aliases the schema: got %v", tag)
+	}
+}
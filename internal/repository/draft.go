@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+)
+
+var draftPattern = regexp.MustCompile(`json-schema\.org/(?:(draft-0\d)|draft/(\d{4}-\d{2}))/schema`)
+
+// GetSchemaDraft returns the JSON Schema draft declared by the "$schema"
+// keyword of the schema under key, e.g. "draft-07" or "2020-12". Unknown
+// meta-schemas are returned verbatim; an undeclared one yields "".
+func (repo *EtcdRepository) GetSchemaDraft(ctx context.Context, key string) (string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetSchemaDraft")
+	defer span.End()
+
+	schemaJson, err := repo.getSchemaJSON(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	var document struct {
+		Schema string `json:"$schema"`
+	}
+	if err := json.Unmarshal(schemaJson, &document); err != nil {
+		return "", err
+	}
+	match := draftPattern.FindStringSubmatch(document.Schema)
+	switch {
+	case match == nil:
+		return document.Schema, nil
+	case match[1] != "":
+		return match[1], nil
+	default:
+		return match[2], nil
+	}
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetSchemaDraft(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	for _, test := range []struct {
+		schema string
+		want   string
+	}{
+		{"$schema: http://json-schema.org/draft-07/schema#\ntype: object\n", "draft-07"},
+		{"$schema: https://json-schema.org/draft/2020-12/schema\ntype: object\n", "2020-12"},
+		{"$schema: https://example.com/meta\ntype: object\n", "https://example.com/meta"},
+		{"type: object\n", ""},
+	} {
+		key := "org/ns/name/v1.0.0"
+		if err := repo.SaveConfigSchema(ctx, key, test.schema); err != nil {
+			t.Fatalf("SaveConfigSchema: %v", err)
+		}
+		draft, err := repo.GetSchemaDraft(ctx, key)
+		if err != nil || draft != test.want {
+			t.Errorf("%q: got %q, %v, want %q", test.schema, draft, err, test.want)
+		}
+		if err := repo.DeleteConfigSchema(ctx, key); err != nil {
+			t.Fatalf("DeleteConfigSchema: %v", err)
+		}
+	}
+	if _, err := repo.GetSchemaDraft(ctx, "org/ns/name/v9.0.0"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing: got %v, want ErrSchemaNotFound", err)
+	}
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EndpointRead describes which cluster member served a pinned read and
+// the revision its local store was at.
+type EndpointRead struct {
+	Endpoint string
+	MemberID uint64
+	Revision int64
+}
+
+// GetConfigSchemaFromEndpoint reads the schema under key from endpoint
+// alone, through a client created for the call. The read is serializable,
+// so it reflects that member's local state, including any replication lag,
+// instead of being confirmed with the leader. The schema is nil if the
+// member does not have the key.
+func (repo *EtcdRepository) GetConfigSchemaFromEndpoint(ctx context.Context, key, endpoint string) (*pb.ConfigSchemaData, *EndpointRead, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetConfigSchemaFromEndpoint")
+	defer span.End()
+
+	if err := repo.validateKey(key); err != nil {
+		return nil, nil, err
+	}
+	if err := repo.checkTenant(ctx, key); err != nil {
+		return nil, nil, err
+	}
+	config := repo.config
+	config.Endpoints = []string{endpoint}
+	config.AutoSyncInterval = 0
+	cli, err := clientv3.New(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cli.Close()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.namespacedKV(cli).Get(ctx, key, clientv3.WithSerializable())
+	if err != nil {
+		repo.recordDeadlineExceeded(ctx, err)
+		return nil, nil, err
+	}
+	read := &EndpointRead{
+		Endpoint: endpoint,
+		MemberID: res.Header.GetMemberId(),
+		Revision: res.Header.GetRevision(),
+	}
+	if len(res.Kvs) == 0 {
+		return nil, read, nil
+	}
+	schema, err := repo.decodeStoredSchema(res.Kvs[0], true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return schema.GetSchemaData(), read, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+func TestGetConfigSchemaFromEndpoint(t *testing.T) {
+	repo, leader := newTestRepo(t)
+	replica := newFakeEtcd(t)
+	ctx := context.Background()
+	key := "org/ns/name/v1.0.0"
+	mustSave(t, repo, key)
+	// The replica lags behind with an older body.
+	stale, err := repo.encodeSchemaData(&pb.ConfigSchemaData{Schema: `{"type":"string"}`})
+	if err != nil {
+		t.Fatalf("encodeSchemaData: %v", err)
+	}
+	revision := replica.put(key, string(stale))
+	leaderRanges := leader.callCount("Range")
+
+	schemaData, read, err := repo.GetConfigSchemaFromEndpoint(ctx, key, replica.addr)
+	if err != nil {
+		t.Fatalf("GetConfigSchemaFromEndpoint: %v", err)
+	}
+	if schemaData.GetSchema() != "type: string\n" {
+		t.Errorf("got body %q, want the replica's", schemaData.GetSchema())
+	}
+	if read.Endpoint != replica.addr || read.Revision != revision {
+		t.Errorf("got %+v, want a read of %s at revision %d", read, replica.addr, revision)
+	}
+	if got := replica.callCount("Range"); got != 1 || !replica.lastRange().GetSerializable() {
+		t.Errorf("the replica served %d reads, last %v", got, replica.lastRange())
+	}
+	if got := leader.callCount("Range") - leaderRanges; got != 0 {
+		t.Errorf("the leader served %d reads", got)
+	}
+
+	schemaData, read, err = repo.GetConfigSchemaFromEndpoint(ctx, "org/ns/name/v2.0.0", replica.addr)
+	if err != nil || schemaData != nil || read == nil || read.Endpoint != replica.addr {
+		t.Errorf("missing on the replica: got %v, %+v, %v", schemaData, read, err)
+	}
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrResultTooLarge         = errors.New("result too large")
+	ErrEmptySchema            = errors.New("schema cannot be empty")
+	ErrInvalidSchema          = errors.New("invalid schema")
+	ErrInvalidConfig          = errors.New("invalid configuration")
+	ErrInvalidConstraint      = errors.New("invalid version constraint")
+	ErrInvalidPatch           = errors.New("invalid patch document")
+	ErrInvalidPath            = errors.New("invalid JSON pointer path")
+	ErrPatchTestFailed        = errors.New("patch test operation failed")
+	ErrConcurrentModification = errors.New("schema was modified concurrently")
+	ErrVersionNotGreater      = errors.New("version is not greater than the latest version")
+	ErrInvalidKey             = errors.New("invalid schema key")
+	ErrReservedVersion        = errors.New("version is reserved and cannot be saved")
+	ErrInvalidLabelSelector   = errors.New("invalid label selector")
+	ErrReadOnly               = errors.New("repository is read-only")
+	ErrEmptyPrefix            = errors.New("prefix cannot be empty")
+	ErrLatestMismatch         = errors.New("latest version does not match the expected version")
+	ErrWatchCompacted         = errors.New("watch revision has been compacted")
+	ErrRevisionCompacted      = errors.New("revision has been compacted")
+	ErrQuotaExceeded          = errors.New("organization storage quota exceeded")
+	ErrInvalidPageToken       = errors.New("invalid page token")
+	ErrNotLeased              = errors.New("schema has no TTL")
+	ErrInvalidSegment         = errors.New("invalid key segment")
+	ErrUnsafeDelete           = errors.New("prefix is too broad to delete without force")
+	ErrSchemaExists           = errors.New("schema already exists")
+	ErrTenantMismatch         = errors.New("key belongs to another tenant")
+	ErrNotWritable            = errors.New("etcd denied write access")
+	ErrAliasNotFound          = errors.New("alias not found")
+	ErrUnsafeArchiveEntry     = errors.New("version cannot be used as an archive entry name")
+	ErrSchemaNotFound         = errors.New("schema not found")
+)
+
+type ResultTooLargeError struct {
+	Count int64
+	Limit int64
+}
+
+func (e *ResultTooLargeError) Error() string {
+	return fmt.Sprintf("prefix scan matched %d schemas, exceeding the limit of %d; narrow the prefix or use the paginated API", e.Count, e.Limit)
+}
+
+func (e *ResultTooLargeError) Unwrap() error {
+	return ErrResultTooLarge
+}
+
+type VersionNotGreaterError struct {
+	Version string
+	Latest  string
+}
+
+func (e *VersionNotGreaterError) Error() string {
+	return fmt.Sprintf("version '%s' does not succeed the latest version '%s'", e.Version, e.Latest)
+}
+
+func (e *VersionNotGreaterError) Unwrap() error {
+	return ErrVersionNotGreater
+}
+
+type LatestMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *LatestMismatchError) Error() string {
+	return fmt.Sprintf("latest version is '%s', expected '%s'", e.Actual, e.Expected)
+}
+
+func (e *LatestMismatchError) Unwrap() error {
+	return ErrLatestMismatch
+}
+
+type WatchCompactedError struct {
+	Revision        int64
+	CompactRevision int64
+}
+
+func (e *WatchCompactedError) Error() string {
+	return fmt.Sprintf("cannot resume watch at revision %d, compacted up to %d", e.Revision, e.CompactRevision)
+}
+
+func (e *WatchCompactedError) Unwrap() error {
+	return ErrWatchCompacted
+}
+
+type SchemaNotFoundError struct {
+	Key    string
+	Prefix bool
+}
+
+func (e *SchemaNotFoundError) Error() string {
+	if e.Prefix {
+		return "No schema with prefix '" + e.Key + "' found!"
+	}
+	return "No schema with key '" + e.Key + "' found!"
+}
+
+func (e *SchemaNotFoundError) Unwrap() error {
+	return ErrSchemaNotFound
+}
+
+type SchemaExistsError struct {
+	Key string
+}
+
+func (e *SchemaExistsError) Error() string {
+	return "Key '" + e.Key + "' already exists!"
+}
+
+func (e *SchemaExistsError) Unwrap() error {
+	return ErrSchemaExists
+}
+
+type QuotaExceededError struct {
+	Organization string
+	Size         int64
+	Limit        int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("organization '%s' would store %d bytes, exceeding its quota of %d", e.Organization, e.Size, e.Limit)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+type InvalidSegmentError struct {
+	Segment string
+	Value   string
+	Pattern string
+}
+
+func (e *InvalidSegmentError) Error() string {
+	return fmt.Sprintf("%s '%s' does not match the pattern %s", e.Segment, e.Value, e.Pattern)
+}
+
+func (e *InvalidSegmentError) Unwrap() error {
+	return ErrInvalidSegment
+}
+
+type UnsafeDeleteError struct {
+	Prefix   string
+	Segments int
+	Required int
+}
+
+func (e *UnsafeDeleteError) Error() string {
+	return fmt.Sprintf("prefix '%s' spans %d key segments, fewer than the %d required without force", e.Prefix, e.Segments, e.Required)
+}
+
+func (e *UnsafeDeleteError) Unwrap() error {
+	return ErrUnsafeDelete
+}
+
+type TenantMismatchError struct {
+	Tenant string
+	Key    string
+}
+
+func (e *TenantMismatchError) Error() string {
+	return fmt.Sprintf("key '%s' is outside the organization of tenant '%s'", e.Key, e.Tenant)
+}
+
+func (e *TenantMismatchError) Unwrap() error {
+	return ErrTenantMismatch
+}
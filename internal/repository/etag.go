@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+func (repo *EtcdRepository) SchemaETag(ctx context.Context, key string) (string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.SchemaETag")
+	defer span.End()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if len(res.Kvs) == 0 {
+		return "", &SchemaNotFoundError{Key: key}
+	}
+	checksum := sha256.Sum256(res.Kvs[0].Value)
+	return `"` + strconv.FormatInt(res.Kvs[0].ModRevision, 10) + "-" + hex.EncodeToString(checksum[:8]) + `"`, nil
+}
+
+// ETagMatches reports whether an If-None-Match header value matches etag,
+// using the weak comparison HTTP mandates for that header.
+func ETagMatches(ifNoneMatch string, etag string) bool {
+	ifNoneMatch = strings.TrimSpace(ifNoneMatch)
+	if ifNoneMatch == "*" {
+		return etag != ""
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSchemaETag(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	key := "org/ns/name/v1.0.0"
+	if _, err := repo.SchemaETag(ctx, key); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing schema: got %v, want ErrSchemaNotFound", err)
+	}
+	mustSave(t, repo, key)
+
+	first, err := repo.SchemaETag(ctx, key)
+	if err != nil {
+		t.Fatalf("SchemaETag: %v", err)
+	}
+	second, err := repo.SchemaETag(ctx, key)
+	if err != nil || second != first {
+		t.Errorf("identical reads: got %q and %q, %v", first, second, err)
+	}
+	if first[0] != '"' || first[len(first)-1] != '"' {
+		t.Errorf("ETag %s is not quoted", first)
+	}
+
+	if err := repo.PatchConfigSchema(ctx, key, []byte(`{"required":["port"]}`)); err != nil {
+		t.Fatalf("PatchConfigSchema: %v", err)
+	}
+	updated, err := repo.SchemaETag(ctx, key)
+	if err != nil || updated == first {
+		t.Errorf("after update: got %q, %v, want a new ETag", updated, err)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	for _, test := range []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{`"7-abc"`, true},
+		{`W/"7-abc"`, true},
+		{`"1-def", "7-abc"`, true},
+		{`*`, true},
+		{`"8-abc"`, false},
+		{``, false},
+	} {
+		if got := ETagMatches(test.ifNoneMatch, `"7-abc"`); got != test.want {
+			t.Errorf("%s: got %t, want %t", test.ifNoneMatch, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,923 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc"
+)
+
+// fakeEtcd is an in-memory, single-member etcd serving the KV, Watch,
+// Lease, Maintenance and Cluster APIs over gRPC, so tests exercise the real
+// etcd client. It keeps every revision until compacted, which is enough
+// for revisioned reads and watches that resume from the past.
+type fakeEtcd struct {
+	pb.UnimplementedKVServer
+	pb.UnimplementedWatchServer
+	pb.UnimplementedLeaseServer
+	pb.UnimplementedMaintenanceServer
+	pb.UnimplementedClusterServer
+
+	addr   string
+	server *grpc.Server
+
+	mu        sync.Mutex
+	revision  int64
+	compacted int64
+	history   map[string][]*mvccpb.KeyValue
+	events    []*mvccpb.Event
+	leases    map[int64]*fakeLease
+	nextLease int64
+	changed   chan struct{}
+	calls     map[string]int
+	failures  map[string][]error
+	// replicaRevision, when set, is the revision serializable reads are
+	// answered at, as if served by a member lagging behind.
+	replicaRevision int64
+	statusErr       error
+	noLeader        bool
+	defragmented    int
+	// drops cancel every open watch as if the server dropped it.
+	drops []func(reason string)
+	gates map[string]*fakeGate
+	// ranges records every Range request, for tests asserting how the
+	// repository reads.
+	ranges []*pb.RangeRequest
+}
+
+// fakeGate holds calls of a method until it is released.
+type fakeGate struct {
+	arrived chan struct{}
+	once    sync.Once
+	release chan struct{}
+}
+
+type fakeLease struct {
+	ttl     int64
+	expires time.Time
+}
+
+func newFakeEtcd(t testing.TB) *fakeEtcd {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	fake := &fakeEtcd{
+		addr:     listener.Addr().String(),
+		server:   grpc.NewServer(),
+		revision: 1,
+		history:  make(map[string][]*mvccpb.KeyValue),
+		leases:   make(map[int64]*fakeLease),
+		changed:  make(chan struct{}),
+		calls:    make(map[string]int),
+		failures: make(map[string][]error),
+	}
+	pb.RegisterKVServer(fake.server, fake)
+	pb.RegisterWatchServer(fake.server, fake)
+	pb.RegisterLeaseServer(fake.server, fake)
+	pb.RegisterMaintenanceServer(fake.server, fake)
+	pb.RegisterClusterServer(fake.server, fake)
+	go fake.server.Serve(listener)
+	t.Cleanup(fake.server.Stop)
+	return fake
+}
+
+// newTestRepo returns a repository backed by a fresh fakeEtcd. opts are
+// applied after the test defaults, which silence logging.
+func newTestRepo(t testing.TB, opts ...Option) (*EtcdRepository, *fakeEtcd) {
+	t.Helper()
+	fake := newFakeEtcd(t)
+	repo, err := NewClient(append([]Option{fake.endpoint(), WithLogger(slog.New(slog.DiscardHandler))}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(repo.Close)
+	return repo, fake
+}
+
+func (fake *fakeEtcd) endpoint() Option {
+	return func(repo *EtcdRepository) {
+		repo.config.Endpoints = []string{fake.addr}
+	}
+}
+
+// failNext makes the next calls of method, e.g. "Put" or "Txn", fail
+// with errs in order.
+func (fake *fakeEtcd) failNext(method string, errs ...error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.failures[method] = append(fake.failures[method], errs...)
+}
+
+func (fake *fakeEtcd) callCount(method string) int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.calls[method]
+}
+
+func (fake *fakeEtcd) currentRevision() int64 {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.revision
+}
+
+// put writes key directly, bypassing the repository.
+func (fake *fakeEtcd) put(key, value string) int64 {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.revision++
+	fake.applyPut(&pb.PutRequest{Key: []byte(key), Value: []byte(value)})
+	fake.notify()
+	return fake.revision
+}
+
+func (fake *fakeEtcd) get(key string) *mvccpb.KeyValue {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.latest(key)
+}
+
+func (fake *fakeEtcd) keys() []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	var keys []string
+	for key := range fake.history {
+		if fake.latest(key) != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// expireLease lets the lease id run out as if its TTL had passed.
+func (fake *fakeEtcd) expireLease(id int64) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if _, ok := fake.leases[id]; ok {
+		fake.revokeLease(id)
+		fake.notify()
+	}
+}
+
+// ageLease brings the expiry of lease id forward by d, as if d had passed.
+func (fake *fakeEtcd) ageLease(id int64, d time.Duration) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if lease, ok := fake.leases[id]; ok {
+		lease.expires = lease.expires.Add(-d)
+	}
+}
+
+func (fake *fakeEtcd) setReplicaRevision(revision int64) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.replicaRevision = revision
+}
+
+func (fake *fakeEtcd) setStatus(err error, noLeader bool) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.statusErr, fake.noLeader = err, noLeader
+}
+
+// defragmentCount returns how many Defragment requests were served.
+func (fake *fakeEtcd) defragmentCount() int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.defragmented
+}
+
+// lastRange returns the most recent Range request.
+func (fake *fakeEtcd) lastRange() *pb.RangeRequest {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.ranges) == 0 {
+		return nil
+	}
+	return fake.ranges[len(fake.ranges)-1]
+}
+
+// dropWatches cancels every open watch with reason, as a server does when
+// it loses its leader.
+func (fake *fakeEtcd) dropWatches(reason string) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for _, drop := range fake.drops {
+		drop(reason)
+	}
+	fake.drops = nil
+}
+
+// openWatches returns how many watches can currently be dropped.
+func (fake *fakeEtcd) openWatches() int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return len(fake.drops)
+}
+
+// hold makes calls of method, currently only "Txn", wait until release is
+// called. arrived is closed once the first call is waiting.
+func (fake *fakeEtcd) hold(method string) (arrived <-chan struct{}, release func()) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.gates == nil {
+		fake.gates = make(map[string]*fakeGate)
+	}
+	gate := &fakeGate{arrived: make(chan struct{}), release: make(chan struct{})}
+	fake.gates[method] = gate
+	return gate.arrived, func() {
+		fake.mu.Lock()
+		delete(fake.gates, method)
+		fake.mu.Unlock()
+		close(gate.release)
+	}
+}
+
+// pass blocks while method is held; it must be called without fake.mu.
+func (fake *fakeEtcd) pass(method string) {
+	fake.mu.Lock()
+	gate := fake.gates[method]
+	fake.mu.Unlock()
+	if gate == nil {
+		return
+	}
+	gate.once.Do(func() { close(gate.arrived) })
+	<-gate.release
+}
+
+// begin counts a call of method and returns its injected failure, if any.
+// It must be called with mu held.
+func (fake *fakeEtcd) begin(method string) error {
+	fake.calls[method]++
+	fake.expireLeases()
+	if errs := fake.failures[method]; len(errs) > 0 {
+		fake.failures[method] = errs[1:]
+		return errs[0]
+	}
+	return nil
+}
+
+func (fake *fakeEtcd) header() *pb.ResponseHeader {
+	return &pb.ResponseHeader{ClusterId: 1, MemberId: 1, Revision: fake.revision, RaftTerm: 1}
+}
+
+func (fake *fakeEtcd) notify() {
+	close(fake.changed)
+	fake.changed = make(chan struct{})
+}
+
+// latest returns the current value of key, nil if it does not exist.
+func (fake *fakeEtcd) latest(key string) *mvccpb.KeyValue {
+	return fake.at(key, fake.revision)
+}
+
+// at returns the value key had at revision, nil if it did not exist.
+func (fake *fakeEtcd) at(key string, revision int64) *mvccpb.KeyValue {
+	versions := fake.history[key]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].ModRevision <= revision {
+			if versions[i].Version == 0 {
+				return nil
+			}
+			return versions[i]
+		}
+	}
+	return nil
+}
+
+func inRange(key, start, end []byte) bool {
+	switch {
+	case len(end) == 0:
+		return bytes.Equal(key, start)
+	case len(end) == 1 && end[0] == 0:
+		return bytes.Compare(key, start) >= 0
+	default:
+		return bytes.Compare(key, start) >= 0 && bytes.Compare(key, end) < 0
+	}
+}
+
+// rangeAt returns the keys in [start, end) as of revision, in key order.
+func (fake *fakeEtcd) rangeAt(start, end []byte, revision int64) []*mvccpb.KeyValue {
+	var kvs []*mvccpb.KeyValue
+	for key := range fake.history {
+		if !inRange([]byte(key), start, end) {
+			continue
+		}
+		if kv := fake.at(key, revision); kv != nil {
+			kvs = append(kvs, kv)
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return bytes.Compare(kvs[i].Key, kvs[j].Key) < 0 })
+	return kvs
+}
+
+func (fake *fakeEtcd) Range(ctx context.Context, req *pb.RangeRequest) (*pb.RangeResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if err := fake.begin("Range"); err != nil {
+		return nil, err
+	}
+	fake.ranges = append(fake.ranges, req)
+	return fake.doRange(req)
+}
+
+func (fake *fakeEtcd) doRange(req *pb.RangeRequest) (*pb.RangeResponse, error) {
+	header := fake.header()
+	revision := fake.revision
+	if req.Serializable && fake.replicaRevision > 0 {
+		revision = fake.replicaRevision
+		header.Revision = revision
+	}
+	if req.Revision > 0 {
+		if req.Revision < fake.compacted {
+			return nil, rpctypes.ErrGRPCCompacted
+		}
+		if req.Revision > fake.revision {
+			return nil, rpctypes.ErrGRPCFutureRev
+		}
+		revision = req.Revision
+	}
+	all := fake.rangeAt(req.Key, req.RangeEnd, revision)
+	res := &pb.RangeResponse{Header: header, Count: int64(len(all))}
+	var kvs []*mvccpb.KeyValue
+	for _, kv := range all {
+		if (req.MinModRevision > 0 && kv.ModRevision < req.MinModRevision) ||
+			(req.MaxModRevision > 0 && kv.ModRevision > req.MaxModRevision) ||
+			(req.MinCreateRevision > 0 && kv.CreateRevision < req.MinCreateRevision) ||
+			(req.MaxCreateRevision > 0 && kv.CreateRevision > req.MaxCreateRevision) {
+			continue
+		}
+		kvs = append(kvs, kv)
+	}
+	if req.SortOrder != pb.RangeRequest_NONE {
+		sortKeyValues(kvs, req.SortTarget, req.SortOrder == pb.RangeRequest_DESCEND)
+	}
+	if req.Limit > 0 && int64(len(kvs)) > req.Limit {
+		kvs = kvs[:req.Limit]
+		res.More = true
+	}
+	if req.CountOnly {
+		return res, nil
+	}
+	for _, kv := range kvs {
+		kv = cloneKeyValue(kv)
+		if req.KeysOnly {
+			kv.Value = nil
+		}
+		res.Kvs = append(res.Kvs, kv)
+	}
+	return res, nil
+}
+
+func sortKeyValues(kvs []*mvccpb.KeyValue, target pb.RangeRequest_SortTarget, descending bool) {
+	less := func(a, b *mvccpb.KeyValue) bool {
+		switch target {
+		case pb.RangeRequest_VERSION:
+			return a.Version < b.Version
+		case pb.RangeRequest_CREATE:
+			return a.CreateRevision < b.CreateRevision
+		case pb.RangeRequest_MOD:
+			return a.ModRevision < b.ModRevision
+		case pb.RangeRequest_VALUE:
+			return bytes.Compare(a.Value, b.Value) < 0
+		}
+		return bytes.Compare(a.Key, b.Key) < 0
+	}
+	sort.SliceStable(kvs, func(i, j int) bool {
+		if descending {
+			return less(kvs[j], kvs[i])
+		}
+		return less(kvs[i], kvs[j])
+	})
+}
+
+func cloneKeyValue(kv *mvccpb.KeyValue) *mvccpb.KeyValue {
+	clone := *kv
+	return &clone
+}
+
+func (fake *fakeEtcd) Put(ctx context.Context, req *pb.PutRequest) (*pb.PutResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if err := fake.begin("Put"); err != nil {
+		return nil, err
+	}
+	if err := fake.checkPut(req); err != nil {
+		return nil, err
+	}
+	fake.revision++
+	res := fake.applyPut(req)
+	fake.notify()
+	return res, nil
+}
+
+func (fake *fakeEtcd) checkPut(req *pb.PutRequest) error {
+	if (req.IgnoreLease || req.IgnoreValue) && fake.latest(string(req.Key)) == nil {
+		return rpctypes.ErrGRPCKeyNotFound
+	}
+	if _, ok := fake.leases[req.Lease]; req.Lease != 0 && !ok {
+		return rpctypes.ErrGRPCLeaseNotFound
+	}
+	return nil
+}
+
+// applyPut writes req at the current revision, which the caller has
+// already advanced.
+func (fake *fakeEtcd) applyPut(req *pb.PutRequest) *pb.PutResponse {
+	key := string(req.Key)
+	prev := fake.latest(key)
+	kv := &mvccpb.KeyValue{
+		Key:            req.Key,
+		Value:          req.Value,
+		Lease:          req.Lease,
+		CreateRevision: fake.revision,
+		ModRevision:    fake.revision,
+		Version:        1,
+	}
+	if prev != nil {
+		kv.CreateRevision = prev.CreateRevision
+		kv.Version = prev.Version + 1
+		if req.IgnoreValue {
+			kv.Value = prev.Value
+		}
+		if req.IgnoreLease {
+			kv.Lease = prev.Lease
+		}
+	}
+	fake.history[key] = append(fake.history[key], kv)
+	event := &mvccpb.Event{Type: mvccpb.PUT, Kv: kv}
+	res := &pb.PutResponse{Header: fake.header()}
+	if prev != nil {
+		event.PrevKv = prev
+		if req.PrevKv {
+			res.PrevKv = cloneKeyValue(prev)
+		}
+	}
+	fake.events = append(fake.events, event)
+	return res
+}
+
+func (fake *fakeEtcd) DeleteRange(ctx context.Context, req *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if err := fake.begin("DeleteRange"); err != nil {
+		return nil, err
+	}
+	if len(fake.rangeAt(req.Key, req.RangeEnd, fake.revision)) == 0 {
+		return &pb.DeleteRangeResponse{Header: fake.header()}, nil
+	}
+	fake.revision++
+	res := fake.applyDelete(req)
+	fake.notify()
+	return res, nil
+}
+
+func (fake *fakeEtcd) applyDelete(req *pb.DeleteRangeRequest) *pb.DeleteRangeResponse {
+	kvs := fake.rangeAt(req.Key, req.RangeEnd, fake.revision)
+	res := &pb.DeleteRangeResponse{Deleted: int64(len(kvs))}
+	for _, kv := range kvs {
+		fake.deleteKey(kv)
+		if req.PrevKv {
+			res.PrevKvs = append(res.PrevKvs, cloneKeyValue(kv))
+		}
+	}
+	res.Header = fake.header()
+	return res
+}
+
+func (fake *fakeEtcd) deleteKey(prev *mvccpb.KeyValue) {
+	tombstone := &mvccpb.KeyValue{Key: prev.Key, ModRevision: fake.revision}
+	fake.history[string(prev.Key)] = append(fake.history[string(prev.Key)], tombstone)
+	fake.events = append(fake.events, &mvccpb.Event{Type: mvccpb.DELETE, Kv: tombstone, PrevKv: prev})
+}
+
+func (fake *fakeEtcd) Txn(ctx context.Context, req *pb.TxnRequest) (*pb.TxnResponse, error) {
+	fake.pass("Txn")
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if err := fake.begin("Txn"); err != nil {
+		return nil, err
+	}
+	if err := fake.checkTxn(req); err != nil {
+		return nil, err
+	}
+	if txnWrites(req) {
+		fake.revision++
+	}
+	res, wrote := fake.applyTxn(req)
+	if wrote {
+		fake.notify()
+	} else if txnWrites(req) {
+		fake.revision--
+		res.Header = fake.header()
+	}
+	return res, nil
+}
+
+func (fake *fakeEtcd) checkTxn(req *pb.TxnRequest) error {
+	ops := req.Failure
+	if fake.compare(req.Compare) {
+		ops = req.Success
+	}
+	for _, op := range ops {
+		switch request := op.Request.(type) {
+		case *pb.RequestOp_RequestPut:
+			if err := fake.checkPut(request.RequestPut); err != nil {
+				return err
+			}
+		case *pb.RequestOp_RequestTxn:
+			if err := fake.checkTxn(request.RequestTxn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// txnWrites reports whether any branch of req may write, in which case it
+// gets a revision of its own.
+func txnWrites(req *pb.TxnRequest) bool {
+	for _, ops := range [][]*pb.RequestOp{req.Success, req.Failure} {
+		for _, op := range ops {
+			switch request := op.Request.(type) {
+			case *pb.RequestOp_RequestPut, *pb.RequestOp_RequestDeleteRange:
+				return true
+			case *pb.RequestOp_RequestTxn:
+				if txnWrites(request.RequestTxn) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// applyTxn applies req at the current revision and reports whether it
+// wrote anything.
+func (fake *fakeEtcd) applyTxn(req *pb.TxnRequest) (*pb.TxnResponse, bool) {
+	res := &pb.TxnResponse{Succeeded: fake.compare(req.Compare)}
+	ops := req.Failure
+	if res.Succeeded {
+		ops = req.Success
+	}
+	wrote := false
+	for _, op := range ops {
+		switch request := op.Request.(type) {
+		case *pb.RequestOp_RequestRange:
+			rangeRes, _ := fake.doRange(request.RequestRange)
+			res.Responses = append(res.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponseRange{ResponseRange: rangeRes}})
+		case *pb.RequestOp_RequestPut:
+			putRes := fake.applyPut(request.RequestPut)
+			res.Responses = append(res.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponsePut{ResponsePut: putRes}})
+			wrote = true
+		case *pb.RequestOp_RequestDeleteRange:
+			deleteRes := fake.applyDelete(request.RequestDeleteRange)
+			res.Responses = append(res.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: deleteRes}})
+			wrote = wrote || deleteRes.Deleted > 0
+		case *pb.RequestOp_RequestTxn:
+			txnRes, txnWrote := fake.applyTxn(request.RequestTxn)
+			res.Responses = append(res.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponseTxn{ResponseTxn: txnRes}})
+			wrote = wrote || txnWrote
+		}
+	}
+	res.Header = fake.header()
+	return res, wrote
+}
+
+func (fake *fakeEtcd) compare(cmps []*pb.Compare) bool {
+	for _, cmp := range cmps {
+		kvs := fake.rangeAt(cmp.Key, cmp.RangeEnd, fake.revision)
+		if len(kvs) == 0 {
+			if cmp.Target == pb.Compare_VALUE {
+				return false
+			}
+			kvs = []*mvccpb.KeyValue{{}}
+		}
+		for _, kv := range kvs {
+			if !compareKeyValue(cmp, kv) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func compareKeyValue(cmp *pb.Compare, kv *mvccpb.KeyValue) bool {
+	var result int
+	switch cmp.Target {
+	case pb.Compare_VALUE:
+		result = bytes.Compare(kv.Value, cmp.GetValue())
+	case pb.Compare_VERSION:
+		result = compareInt(kv.Version, cmp.GetVersion())
+	case pb.Compare_CREATE:
+		result = compareInt(kv.CreateRevision, cmp.GetCreateRevision())
+	case pb.Compare_MOD:
+		result = compareInt(kv.ModRevision, cmp.GetModRevision())
+	case pb.Compare_LEASE:
+		result = compareInt(kv.Lease, cmp.GetLease())
+	}
+	switch cmp.Result {
+	case pb.Compare_EQUAL:
+		return result == 0
+	case pb.Compare_NOT_EQUAL:
+		return result != 0
+	case pb.Compare_GREATER:
+		return result > 0
+	default:
+		return result < 0
+	}
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (fake *fakeEtcd) Compact(ctx context.Context, req *pb.CompactionRequest) (*pb.CompactionResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if err := fake.begin("Compact"); err != nil {
+		return nil, err
+	}
+	if req.Revision <= fake.compacted {
+		return nil, rpctypes.ErrGRPCCompacted
+	}
+	if req.Revision > fake.revision {
+		return nil, rpctypes.ErrGRPCFutureRev
+	}
+	fake.compacted = req.Revision
+	fake.notify()
+	return &pb.CompactionResponse{Header: fake.header()}, nil
+}
+
+func (fake *fakeEtcd) Watch(stream pb.Watch_WatchServer) error {
+	var sendMu sync.Mutex
+	send := func(res *pb.WatchResponse) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(res)
+	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	cancels := make(map[int64]context.CancelFunc)
+	var nextID int64
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		switch request := req.RequestUnion.(type) {
+		case *pb.WatchRequest_CreateRequest:
+			fake.mu.Lock()
+			fake.calls["Watch"]++
+			header := fake.header()
+			fake.mu.Unlock()
+			id := nextID
+			nextID++
+			watchCtx, watchCancel := context.WithCancel(ctx)
+			cancels[id] = watchCancel
+			if err := send(&pb.WatchResponse{Header: header, WatchId: id, Created: true}); err != nil {
+				return nil
+			}
+			fake.mu.Lock()
+			fake.drops = append(fake.drops, func(reason string) {
+				watchCancel()
+				send(&pb.WatchResponse{Header: fake.header(), WatchId: id, Canceled: true, CancelReason: reason})
+			})
+			fake.mu.Unlock()
+			go fake.serveWatch(watchCtx, id, request.CreateRequest, header.Revision, send)
+		case *pb.WatchRequest_CancelRequest:
+			id := request.CancelRequest.WatchId
+			if watchCancel, ok := cancels[id]; ok {
+				watchCancel()
+				delete(cancels, id)
+				fake.mu.Lock()
+				header := fake.header()
+				fake.mu.Unlock()
+				send(&pb.WatchResponse{Header: header, WatchId: id, Canceled: true})
+			}
+		case *pb.WatchRequest_ProgressRequest:
+			fake.mu.Lock()
+			header := fake.header()
+			fake.mu.Unlock()
+			send(&pb.WatchResponse{Header: header, WatchId: -1})
+		}
+	}
+}
+
+func (fake *fakeEtcd) serveWatch(ctx context.Context, id int64, req *pb.WatchCreateRequest, createdAt int64, send func(*pb.WatchResponse) error) {
+	next := req.StartRevision
+	if next <= 0 {
+		next = createdAt + 1
+	}
+	filtered := make(map[mvccpb.Event_EventType]bool)
+	for _, filter := range req.Filters {
+		switch filter {
+		case pb.WatchCreateRequest_NOPUT:
+			filtered[mvccpb.PUT] = true
+		case pb.WatchCreateRequest_NODELETE:
+			filtered[mvccpb.DELETE] = true
+		}
+	}
+	for {
+		fake.mu.Lock()
+		if ctx.Err() != nil {
+			fake.mu.Unlock()
+			return
+		}
+		if next <= fake.compacted {
+			header := fake.header()
+			compacted := fake.compacted
+			fake.mu.Unlock()
+			send(&pb.WatchResponse{Header: header, WatchId: id, CompactRevision: compacted, Canceled: true})
+			return
+		}
+		var batches [][]*mvccpb.Event
+		for _, event := range fake.events {
+			if event.Kv.ModRevision < next || filtered[event.Type] || !inRange(event.Kv.Key, req.Key, req.RangeEnd) {
+				continue
+			}
+			if !req.PrevKv {
+				event = &mvccpb.Event{Type: event.Type, Kv: event.Kv}
+			}
+			if n := len(batches); n > 0 && batches[n-1][0].Kv.ModRevision == event.Kv.ModRevision {
+				batches[n-1] = append(batches[n-1], event)
+			} else {
+				batches = append(batches, []*mvccpb.Event{event})
+			}
+		}
+		next = fake.revision + 1
+		changed := fake.changed
+		fake.mu.Unlock()
+		for _, batch := range batches {
+			header := &pb.ResponseHeader{ClusterId: 1, MemberId: 1, Revision: batch[0].Kv.ModRevision, RaftTerm: 1}
+			if err := send(&pb.WatchResponse{Header: header, WatchId: id, Events: batch}); err != nil {
+				return
+			}
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (fake *fakeEtcd) LeaseGrant(ctx context.Context, req *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if err := fake.begin("LeaseGrant"); err != nil {
+		return nil, err
+	}
+	id := req.ID
+	if id == 0 {
+		fake.nextLease++
+		id = fake.nextLease
+	}
+	fake.leases[id] = &fakeLease{ttl: req.TTL, expires: time.Now().Add(time.Duration(req.TTL) * time.Second)}
+	return &pb.LeaseGrantResponse{Header: fake.header(), ID: id, TTL: req.TTL}, nil
+}
+
+func (fake *fakeEtcd) LeaseRevoke(ctx context.Context, req *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if err := fake.begin("LeaseRevoke"); err != nil {
+		return nil, err
+	}
+	if _, ok := fake.leases[req.ID]; !ok {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+	fake.revokeLease(req.ID)
+	fake.notify()
+	return &pb.LeaseRevokeResponse{Header: fake.header()}, nil
+}
+
+// revokeLease drops the lease id and deletes its keys in one revision.
+func (fake *fakeEtcd) revokeLease(id int64) {
+	delete(fake.leases, id)
+	var attached []*mvccpb.KeyValue
+	for key := range fake.history {
+		if kv := fake.latest(key); kv != nil && kv.Lease == id {
+			attached = append(attached, kv)
+		}
+	}
+	if len(attached) == 0 {
+		return
+	}
+	fake.revision++
+	for _, kv := range attached {
+		fake.deleteKey(kv)
+	}
+}
+
+func (fake *fakeEtcd) expireLeases() {
+	for id, lease := range fake.leases {
+		if time.Now().After(lease.expires) {
+			fake.revokeLease(id)
+			fake.notify()
+		}
+	}
+}
+
+func (fake *fakeEtcd) LeaseKeepAlive(stream pb.Lease_LeaseKeepAliveServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fake.mu.Lock()
+		fake.calls["LeaseKeepAlive"]++
+		fake.expireLeases()
+		res := &pb.LeaseKeepAliveResponse{Header: fake.header(), ID: req.ID}
+		if lease, ok := fake.leases[req.ID]; ok {
+			lease.expires = time.Now().Add(time.Duration(lease.ttl) * time.Second)
+			res.TTL = lease.ttl
+		}
+		fake.mu.Unlock()
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+func (fake *fakeEtcd) LeaseTimeToLive(ctx context.Context, req *pb.LeaseTimeToLiveRequest) (*pb.LeaseTimeToLiveResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if err := fake.begin("LeaseTimeToLive"); err != nil {
+		return nil, err
+	}
+	res := &pb.LeaseTimeToLiveResponse{Header: fake.header(), ID: req.ID, TTL: -1}
+	if lease, ok := fake.leases[req.ID]; ok {
+		res.TTL = int64(time.Until(lease.expires).Round(time.Second) / time.Second)
+		res.GrantedTTL = lease.ttl
+	}
+	return res, nil
+}
+
+func (fake *fakeEtcd) LeaseLeases(ctx context.Context, req *pb.LeaseLeasesRequest) (*pb.LeaseLeasesResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	res := &pb.LeaseLeasesResponse{Header: fake.header()}
+	for id := range fake.leases {
+		res.Leases = append(res.Leases, &pb.LeaseStatus{ID: id})
+	}
+	return res, nil
+}
+
+func (fake *fakeEtcd) Status(ctx context.Context, req *pb.StatusRequest) (*pb.StatusResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if err := fake.begin("Status"); err != nil {
+		return nil, err
+	}
+	if fake.statusErr != nil {
+		return nil, fake.statusErr
+	}
+	res := &pb.StatusResponse{Header: fake.header(), Version: "3.5.11", Leader: 1, RaftIndex: uint64(fake.revision), RaftTerm: 1}
+	if fake.noLeader {
+		res.Leader = 0
+	}
+	return res, nil
+}
+
+func (fake *fakeEtcd) Defragment(ctx context.Context, req *pb.DefragmentRequest) (*pb.DefragmentResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if err := fake.begin("Defragment"); err != nil {
+		return nil, err
+	}
+	fake.defragmented++
+	return &pb.DefragmentResponse{Header: fake.header()}, nil
+}
+
+func (fake *fakeEtcd) MemberList(ctx context.Context, req *pb.MemberListRequest) (*pb.MemberListResponse, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if err := fake.begin("MemberList"); err != nil {
+		return nil, err
+	}
+	return &pb.MemberListResponse{
+		Header:  fake.header(),
+		Members: []*pb.Member{{ID: 1, Name: "fake", ClientURLs: []string{"http://" + fake.addr}}},
+	}, nil
+}
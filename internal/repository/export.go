@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+type ExportFilter struct {
+	// LabelSelector is a comma separated list of "key=value", "key!=value",
+	// "key" and "!key" requirements, all of which must hold.
+	LabelSelector string
+	// CreatedAfter and CreatedBefore bound the creation time (inclusive
+	// and exclusive respectively); zero values leave the range open.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+func (repo *EtcdRepository) Export(ctx context.Context, prefix string, w io.Writer) error {
+	return repo.ExportFiltered(ctx, prefix, ExportFilter{}, w)
+}
+
+// ExportFiltered writes every schema under prefix that satisfies filter to w
+// as newline delimited JSON. etcd cannot query labels, so filtering happens
+// in memory while streaming the prefix with StreamAll.
+func (repo *EtcdRepository) ExportFiltered(ctx context.Context, prefix string, filter ExportFilter, w io.Writer) error {
+	ctx, span := repo.startSpan(ctx, "Repository.ExportFiltered")
+	defer span.End()
+
+	selector, err := parseLabelSelector(filter.LabelSelector)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	encoder := json.NewEncoder(w)
+	schemas, errs := repo.StreamAll(ctx, prefix)
+	for schema := range schemas {
+		if !filter.matches(selector, schema) {
+			continue
+		}
+		if err := encoder.Encode(schema); err != nil {
+			return err
+		}
+	}
+	return <-errs
+}
+
+func (filter ExportFilter) matches(selector labelSelector, schema *pb.ConfigSchema) bool {
+	if !selector.matches(schema.GetSchemaData().GetLabels()) {
+		return false
+	}
+	creationTime := schema.GetSchemaData().GetCreationTime().AsTime()
+	if !filter.CreatedAfter.IsZero() && creationTime.Before(filter.CreatedAfter) {
+		return false
+	}
+	if !filter.CreatedBefore.IsZero() && !creationTime.Before(filter.CreatedBefore) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+func exportedVersions(t *testing.T, export *bytes.Buffer) []string {
+	t.Helper()
+	var versions []string
+	decoder := json.NewDecoder(export)
+	for decoder.More() {
+		var schema pb.ConfigSchema
+		if err := decoder.Decode(&schema); err != nil {
+			t.Fatalf("decoding export: %v", err)
+		}
+		versions = append(versions, schema.GetSchemaDetails().GetVersion())
+	}
+	return versions
+}
+
+func TestExportFiltered(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mustSave(t, repo, "org/ns/name/v1.0.0", WithLabels(map[string]string{"env": "prod"}), WithCreationTime(day))
+	mustSave(t, repo, "org/ns/name/v1.1.0", WithLabels(map[string]string{"env": "dev"}), WithCreationTime(day))
+	mustSave(t, repo, "org/ns/name/v1.2.0", WithLabels(map[string]string{"env": "prod", "tier": "gold"}), WithCreationTime(day.Add(48*time.Hour)))
+	mustSave(t, repo, "org/ns/name/v1.3.0", WithCreationTime(day))
+
+	for _, test := range []struct {
+		name   string
+		filter ExportFilter
+		want   []string
+	}{
+		{"everything", ExportFilter{}, []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0"}},
+		{"equality", ExportFilter{LabelSelector: "env=prod"}, []string{"v1.0.0", "v1.2.0"}},
+		{"existence", ExportFilter{LabelSelector: "env,!tier"}, []string{"v1.0.0", "v1.1.0"}},
+		{"time range", ExportFilter{LabelSelector: "env=prod", CreatedAfter: day.Add(time.Hour)}, []string{"v1.2.0"}},
+		{"before", ExportFilter{CreatedBefore: day.Add(time.Hour)}, []string{"v1.0.0", "v1.1.0", "v1.3.0"}},
+	} {
+		var export bytes.Buffer
+		if err := repo.ExportFiltered(context.Background(), "org/ns/name/", test.filter, &export); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := exportedVersions(t, &export); !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
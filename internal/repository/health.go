@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// HealthWatchdog tracks whether etcd is reachable, as last observed by the
+// periodic probe started with WatchHealth.
+type HealthWatchdog struct {
+	healthy atomic.Bool
+	changes chan bool
+}
+
+// Healthy returns the result of the last probe; false before the first.
+func (watchdog *HealthWatchdog) Healthy() bool {
+	return watchdog.healthy.Load()
+}
+
+// Changes emits the new health whenever it flips, starting with the result
+// of the first probe. A slow reader only ever sees the latest state. The
+// channel is closed when the watchdog stops.
+func (watchdog *HealthWatchdog) Changes() <-chan bool {
+	return watchdog.changes
+}
+
+func (watchdog *HealthWatchdog) publish(healthy bool) {
+	select {
+	case <-watchdog.changes:
+	default:
+	}
+	watchdog.changes <- healthy
+}
+
+// WatchHealth probes the status of the configured endpoints every interval
+// until ctx is canceled. etcd counts as healthy while at least one endpoint
+// answers, reports no errors and knows a leader.
+func (repo *EtcdRepository) WatchHealth(ctx context.Context, interval time.Duration) *HealthWatchdog {
+	watchdog := &HealthWatchdog{changes: make(chan bool, 1)}
+	go func() {
+		defer close(watchdog.changes)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		first := true
+		for {
+			healthy := repo.probeHealth(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if first || watchdog.healthy.Load() != healthy {
+				repo.logger.InfoContext(ctx, "etcd health changed", "healthy", healthy)
+				watchdog.healthy.Store(healthy)
+				watchdog.publish(healthy)
+				first = false
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return watchdog
+}
+
+func (repo *EtcdRepository) probeHealth(ctx context.Context) bool {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	cli := repo.etcdClient()
+	for _, endpoint := range cli.Endpoints() {
+		res, err := cli.Status(ctx, endpoint)
+		if err == nil && len(res.Errors) == 0 && res.Leader != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWatchHealthReportsTransitions(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watchdog := repo.WatchHealth(ctx, 10*time.Millisecond)
+	next := func(want bool) {
+		t.Helper()
+		select {
+		case healthy, ok := <-watchdog.Changes():
+			if !ok {
+				t.Fatalf("the changes channel was closed")
+			}
+			if healthy != want || watchdog.Healthy() != want {
+				t.Fatalf("got healthy %t (last probe %t), want %t", healthy, watchdog.Healthy(), want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no transition to healthy %t", want)
+		}
+	}
+
+	next(true)
+	fake.setStatus(nil, true)
+	next(false)
+	fake.setStatus(nil, false)
+	next(true)
+	fake.setStatus(status.Error(codes.Internal, "injected outage"), false)
+	next(false)
+
+	cancel()
+	select {
+	case _, ok := <-watchdog.Changes():
+		if ok {
+			t.Errorf("got a transition after cancel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the changes channel was not closed on cancel")
+	}
+}
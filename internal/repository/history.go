@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// VersionInfo is the metadata of one stored version, without its body.
+// Checksum is the hex SHA-256 of the schema as stored (JSON); Author is
+// empty for versions saved without WithAuthor.
+type VersionInfo struct {
+	Version      string
+	CreationTime time.Time
+	Author       string
+	Checksum     string
+	SizeBytes    int64
+}
+
+func (repo *EtcdRepository) GetVersionHistory(ctx context.Context, org, namespace, name string) ([]VersionInfo, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetVersionHistory")
+	defer span.End()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, repo.getSchemaPrefix(org, namespace, name), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	history := make([]VersionInfo, 0, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		schemaDetails, err := repo.getSchemaDetailsFromKey(string(kv.Key))
+		if err != nil {
+			return nil, err
+		}
+		if isReservedVersion(schemaDetails.GetVersion()) {
+			continue
+		}
+		var schemaData pb.ConfigSchemaData
+		if err := repo.decodeSchemaData(kv.Value, &schemaData); err != nil {
+			return nil, err
+		}
+		checksum := sha256.Sum256([]byte(schemaData.GetSchema()))
+		history = append(history, VersionInfo{
+			Version:      schemaDetails.GetVersion(),
+			CreationTime: schemaData.GetCreationTime().AsTime(),
+			Author:       schemaData.GetAuthor(),
+			Checksum:     hex.EncodeToString(checksum[:]),
+			SizeBytes:    int64(len(kv.Value)),
+		})
+	}
+	sort.Slice(history, func(i, j int) bool {
+		return repo.comparator.Compare(history[i].Version, history[j].Version) == -1
+	})
+	return history, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGetVersionHistory(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	base := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	authors := map[string]string{"v1.10.0": "ana", "v1.2.0": "", "v1.9.0": "marko"}
+	for i, version := range []string{"v1.10.0", "v1.2.0", "v1.9.0"} {
+		opts := []SaveOption{WithCreationTime(base.Add(time.Duration(i) * time.Hour))}
+		if authors[version] != "" {
+			opts = append(opts, WithAuthor(authors[version]))
+		}
+		mustSave(t, repo, "org/ns/name/"+version, opts...)
+	}
+	mustSave(t, repo, "org/ns/name-other/v0.1.0")
+
+	history, err := repo.GetVersionHistory(context.Background(), "org", "ns", "name")
+	if err != nil {
+		t.Fatalf("GetVersionHistory: %v", err)
+	}
+	var versions []string
+	for _, info := range history {
+		versions = append(versions, info.Version)
+	}
+	if want := []string{"v1.2.0", "v1.9.0", "v1.10.0"}; !slices.Equal(versions, want) {
+		t.Fatalf("got %v, want %v", versions, want)
+	}
+	for i, info := range history {
+		key := "org/ns/name/" + info.Version
+		checksum := sha256.Sum256([]byte(storedData(t, fake, key).GetSchema()))
+		if info.Checksum != hex.EncodeToString(checksum[:]) {
+			t.Errorf("%s: checksum %s", info.Version, info.Checksum)
+		}
+		if info.SizeBytes != int64(len(fake.get(key).Value)) {
+			t.Errorf("%s: size %d, want %d", info.Version, info.SizeBytes, len(fake.get(key).Value))
+		}
+		if info.Author != authors[info.Version] {
+			t.Errorf("%s: author %q, want %q", info.Version, info.Author, authors[info.Version])
+		}
+		if want := base.Add(time.Duration([]int{1, 2, 0}[i]) * time.Hour); !info.CreationTime.Equal(want) {
+			t.Errorf("%s: created %v, want %v", info.Version, info.CreationTime, want)
+		}
+	}
+
+	history, err = repo.GetVersionHistory(context.Background(), "org", "ns", "missing")
+	if err != nil || len(history) != 0 {
+		t.Errorf("missing name: got %v, %v", history, err)
+	}
+}
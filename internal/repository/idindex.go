@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// idIndexPrefix holds the secondary index from a schema's "$id" to the key
+// it is stored under, per organization. When several schemas of an
+// organization declare the same $id, the index points at the one written
+// last.
+const idIndexPrefix = "_index/id/"
+
+func schemaID(schemaJson string) string {
+	var document struct {
+		ID string `json:"$id"`
+	}
+	if err := json.Unmarshal([]byte(schemaJson), &document); err != nil {
+		return ""
+	}
+	return document.ID
+}
+
+// idIndexKey returns the index entry for id in the organization of the
+// schema key, or "" if key is not a schema key.
+func (repo *EtcdRepository) idIndexKey(key, id string) string {
+	schemaDetails, err := repo.getSchemaDetailsFromKey(key)
+	if err != nil {
+		return ""
+	}
+	return internalKey(idIndexPrefix, schemaDetails.GetOrganization(), id)
+}
+
+func (repo *EtcdRepository) idIndexOps(key, schemaJson string) []clientv3.Op {
+	id := schemaID(schemaJson)
+	if id == "" {
+		return nil
+	}
+	indexKey := repo.idIndexKey(key, id)
+	if indexKey == "" {
+		return nil
+	}
+	return []clientv3.Op{clientv3.OpPut(indexKey, key)}
+}
+
+// removeIDIndex drops the index entry for id unless it has meanwhile been
+// repointed at a schema other than key.
+func (repo *EtcdRepository) removeIDIndex(ctx context.Context, id, key string) error {
+	indexKey := repo.idIndexKey(key, id)
+	if indexKey == "" {
+		return nil
+	}
+	_, err := repo.kv.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(indexKey), "=", key)).
+		Then(clientv3.OpDelete(indexKey)).
+		Commit()
+	return err
+}
+
+func (repo *EtcdRepository) unindexDeleted(ctx context.Context, deleted []*mvccpb.KeyValue) error {
+	for _, kv := range deleted {
+		var schemaData pb.ConfigSchemaData
+		if err := repo.decodeSchemaData(kv.Value, &schemaData); err != nil {
+			continue
+		}
+		if id := schemaID(schemaData.GetSchema()); id != "" {
+			if err := repo.removeIDIndex(ctx, id, string(kv.Key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetSchemaByID returns the schema of org whose "$id" is id.
+func (repo *EtcdRepository) GetSchemaByID(ctx context.Context, org, id string) (*pb.ConfigSchema, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetSchemaByID")
+	defer span.End()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, internalKey(idIndexPrefix, org, id))
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, &SchemaNotFoundError{Key: id}
+	}
+	key := string(res.Kvs[0].Value)
+	res, err = repo.kv.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, &SchemaNotFoundError{Key: key}
+	}
+	return repo.decodeConfigSchema(res.Kvs[0])
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+const idSchema = "$id: https://example.com/port.json\ntype: object\n"
+
+func TestGetSchemaByID(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	indexKey := internalKey(idIndexPrefix, "org", "https://example.com/port.json")
+	if err := repo.SaveConfigSchema(ctx, "org/ns/name/v1.0.0", idSchema); err != nil {
+		t.Fatalf("SaveConfigSchema: %v", err)
+	}
+	if kv := fake.get(indexKey); kv == nil || string(kv.Value) != "org/ns/name/v1.0.0" {
+		t.Fatalf("index entry: got %v", kv)
+	}
+
+	schema, err := repo.GetSchemaByID(ctx, "org", "https://example.com/port.json")
+	if err != nil || schema.GetSchemaDetails().GetVersion() != "v1.0.0" {
+		t.Fatalf("got %v, %v", schema.GetSchemaDetails(), err)
+	}
+	if _, err := repo.GetSchemaByID(ctx, "org", "https://example.com/other.json"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("unknown id: got %v, want ErrSchemaNotFound", err)
+	}
+	schemas, err := repo.GetSchemasByPrefix(ctx, "")
+	if err != nil || len(schemas) != 1 {
+		t.Errorf("the index entry is listed as a schema: %v, %v", schemaVersions(schemas), err)
+	}
+
+	if err := repo.DeleteConfigSchema(ctx, "org/ns/name/v1.0.0"); err != nil {
+		t.Fatalf("DeleteConfigSchema: %v", err)
+	}
+	if fake.get(indexKey) != nil {
+		t.Errorf("the index entry outlived its schema")
+	}
+}
+
+func TestIDIndexFollowsLastWrite(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	indexKey := internalKey(idIndexPrefix, "org", "https://example.com/port.json")
+	for _, version := range []string{"v1.0.0", "v1.1.0"} {
+		if err := repo.SaveConfigSchema(ctx, "org/ns/name/"+version, idSchema); err != nil {
+			t.Fatalf("SaveConfigSchema(%s): %v", version, err)
+		}
+	}
+
+	// Deleting a version the index no longer points at keeps the entry.
+	if err := repo.DeleteConfigSchema(ctx, "org/ns/name/v1.0.0"); err != nil {
+		t.Fatalf("DeleteConfigSchema: %v", err)
+	}
+	if kv := fake.get(indexKey); kv == nil || string(kv.Value) != "org/ns/name/v1.1.0" {
+		t.Errorf("index entry: got %v, want org/ns/name/v1.1.0", kv)
+	}
+
+	if _, err := repo.DeleteSchemasByPrefix(ctx, "org/ns/name/"); err != nil {
+		t.Fatalf("DeleteSchemasByPrefix: %v", err)
+	}
+	if fake.get(indexKey) != nil {
+		t.Errorf("a prefix delete left the index entry behind")
+	}
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const defaultPageSize = 100
+
+// SchemaIterator walks all schemas under a prefix in key order, fetching
+// them from etcd one page at a time. Every page is read at the revision
+// of the first one, so the iteration observes a consistent snapshot.
+// A SchemaIterator must not be shared between goroutines.
+type SchemaIterator struct {
+	repo     *EtcdRepository
+	ctx      context.Context
+	nextKey  string
+	rangeEnd string
+	revision int64
+	pageSize int64
+	page     []*mvccpb.KeyValue
+	current  *pb.ConfigSchema
+	done     bool
+	err      error
+}
+
+func (repo *EtcdRepository) IterateSchemasByPrefix(ctx context.Context, prefix string) *SchemaIterator {
+	return &SchemaIterator{
+		repo:     repo,
+		ctx:      ctx,
+		nextKey:  prefix,
+		rangeEnd: clientv3.GetPrefixRangeEnd(prefix),
+		pageSize: repo.iteratorPageSize,
+	}
+}
+
+func (it *SchemaIterator) Next() bool {
+	kv, ok := it.nextKeyValue()
+	if !ok {
+		return false
+	}
+	schema, err := it.repo.decodeConfigSchema(kv)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.current = schema
+	return true
+}
+
+// nextKeyValue advances to the next schema without decoding it.
+func (it *SchemaIterator) nextKeyValue() (*mvccpb.KeyValue, bool) {
+	if it.err != nil {
+		return nil, false
+	}
+	for len(it.page) == 0 || it.repo.isReservedKey(string(it.page[0].Key)) {
+		if len(it.page) > 0 {
+			it.page = it.page[1:]
+			continue
+		}
+		if it.done {
+			return nil, false
+		}
+		if err := it.fetchPage(); err != nil {
+			it.err = err
+			return nil, false
+		}
+	}
+	kv := it.page[0]
+	it.page = it.page[1:]
+	return kv, true
+}
+
+func (it *SchemaIterator) Schema() *pb.ConfigSchema {
+	return it.current
+}
+
+func (it *SchemaIterator) Err() error {
+	return it.err
+}
+
+func (it *SchemaIterator) fetchPage() error {
+	ctx, span := it.repo.startSpan(it.ctx, "Repository.SchemaIterator.fetchPage")
+	defer span.End()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	getOpts := []clientv3.OpOption{
+		clientv3.WithRange(it.rangeEnd),
+		clientv3.WithLimit(it.pageSize),
+	}
+	if it.revision > 0 {
+		getOpts = append(getOpts, clientv3.WithRev(it.revision))
+	}
+	res, err := it.repo.kv.Get(ctx, it.nextKey, getOpts...)
+	if err != nil {
+		return err
+	}
+	if it.revision == 0 {
+		it.revision = res.Header.Revision
+	}
+	it.page = res.Kvs
+	if !res.More || len(res.Kvs) == 0 {
+		it.done = true
+	} else {
+		it.nextKey = string(res.Kvs[len(res.Kvs)-1].Key) + "\x00"
+	}
+	return nil
+}
+
+// StreamAll emits every schema under prefix, paging through etcd like
+// IterateSchemasByPrefix so memory stays bounded. Entries come in key
+// order unless WithStreamConcurrency allows several to be decoded at once,
+// in which case each is still emitted exactly once. The schema channel is
+// closed when the stream ends; the error channel then yields the error
+// that ended it early, if any, including ctx's error on cancellation, and
+// is closed as well.
+func (repo *EtcdRepository) StreamAll(ctx context.Context, prefix string) (<-chan *pb.ConfigSchema, <-chan error) {
+	schemas := make(chan *pb.ConfigSchema)
+	errs := make(chan error, 1)
+	if repo.streamWorkers > 1 {
+		go repo.streamConcurrently(ctx, prefix, schemas, errs)
+		return schemas, errs
+	}
+	go func() {
+		defer close(errs)
+		defer close(schemas)
+		it := repo.IterateSchemasByPrefix(ctx, prefix)
+		for it.Next() {
+			select {
+			case schemas <- it.Schema():
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+		if err := it.Err(); err != nil {
+			errs <- err
+		}
+	}()
+	return schemas, errs
+}
+
+// streamConcurrently fans the raw entries out to repo.streamWorkers
+// decoding workers. The entry channel holds at most one entry per worker,
+// so a slow consumer stalls the pager instead of growing memory.
+func (repo *EtcdRepository) streamConcurrently(ctx context.Context, prefix string, schemas chan<- *pb.ConfigSchema, errs chan<- error) {
+	defer close(errs)
+	defer close(schemas)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	fail := func(err error) {
+		select {
+		case errs <- err:
+			cancel()
+		default:
+		}
+	}
+
+	kvs := make(chan *mvccpb.KeyValue, repo.streamWorkers)
+	var workers sync.WaitGroup
+	for i := 0; i < repo.streamWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for kv := range kvs {
+				schema, err := repo.decodeConfigSchema(kv)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				select {
+				case schemas <- schema:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	it := repo.IterateSchemasByPrefix(ctx, prefix)
+	produce := func() {
+		for {
+			kv, ok := it.nextKeyValue()
+			if !ok {
+				return
+			}
+			select {
+			case kvs <- kv:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+	produce()
+	close(kvs)
+	workers.Wait()
+	if err := it.Err(); err != nil {
+		fail(err)
+	}
+	if err := ctx.Err(); err != nil {
+		fail(err)
+	}
+}
@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSchemaIteratorPagesThroughPrefix(t *testing.T) {
+	repo, fake := newTestRepo(t, WithIteratorPageSize(2))
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0", "v1.4.0")
+	ranges := fake.callCount("Range")
+
+	var versions []string
+	it := repo.IterateSchemasByPrefix(context.Background(), "org/ns/name/")
+	for it.Next() {
+		versions = append(versions, it.Schema().GetSchemaDetails().GetVersion())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if want := []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0", "v1.4.0"}; !slices.Equal(versions, want) {
+		t.Errorf("got %v, want %v", versions, want)
+	}
+	if pages := fake.callCount("Range") - ranges; pages < 3 {
+		t.Errorf("fetched %d pages, want at least 3", pages)
+	}
+}
+
+func TestSchemaIteratorSurfacesPageErrors(t *testing.T) {
+	repo, fake := newTestRepo(t, WithIteratorPageSize(2))
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0")
+	injected := status.Error(codes.FailedPrecondition, "injected failure")
+	fake.failNext("Range", nil, injected)
+
+	seen := 0
+	it := repo.IterateSchemasByPrefix(context.Background(), "org/ns/name/")
+	for it.Next() {
+		seen++
+	}
+	if status.Code(it.Err()) != codes.FailedPrecondition {
+		t.Fatalf("got %v, want the injected failure", it.Err())
+	}
+	// The first page also holds the latest pointer, which is skipped.
+	if seen == 0 || seen == 4 {
+		t.Errorf("saw %d schemas, want only those of the first page", seen)
+	}
+	if it.Next() {
+		t.Errorf("Next succeeded after a failure")
+	}
+}
+
+func TestStreamAllCoversPrefix(t *testing.T) {
+	repo, fake := newTestRepo(t, WithIteratorPageSize(2))
+	want := []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0", "v1.4.0", "v1.5.0", "v1.6.0"}
+	saveVersions(t, repo, "org/ns/name/", want...)
+	mustSave(t, repo, "org/ns/other/v9.0.0")
+	ranges := fake.callCount("Range")
+
+	schemas, errs := repo.StreamAll(context.Background(), "org/ns/name/")
+	var versions []string
+	for schema := range schemas {
+		versions = append(versions, schema.GetSchemaDetails().GetVersion())
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if !slices.Equal(versions, want) {
+		t.Errorf("got %v, want %v", versions, want)
+	}
+	if pages := fake.callCount("Range") - ranges; pages < 4 {
+		t.Errorf("fetched %d pages, want at least 4", pages)
+	}
+	if _, open := <-errs; open {
+		t.Errorf("error channel left open")
+	}
+}
+
+func TestStreamAllSurfacesPageErrors(t *testing.T) {
+	repo, fake := newTestRepo(t, WithIteratorPageSize(2))
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0")
+	fake.failNext("Range", nil, status.Error(codes.FailedPrecondition, "injected failure"))
+
+	schemas, errs := repo.StreamAll(context.Background(), "org/ns/name/")
+	seen := 0
+	for range schemas {
+		seen++
+	}
+	if err := <-errs; status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("got %v, want the injected failure", err)
+	}
+	if seen == 0 || seen == 4 {
+		t.Errorf("saw %d schemas, want only those of the first page", seen)
+	}
+}
+
+func TestStreamAllStopsOnCancellation(t *testing.T) {
+	// One page holds everything, so the stream is only ever waiting on its
+	// consumer when the context is cancelled.
+	repo, fake := newTestRepo(t, WithIteratorPageSize(100))
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0")
+	ranges := fake.callCount("Range")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	schemas, errs := repo.StreamAll(ctx, "org/ns/name/")
+	if first := <-schemas; first.GetSchemaDetails().GetVersion() != "v1.0.0" {
+		t.Fatalf("first schema: got %v", first.GetSchemaDetails())
+	}
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if schema, open := <-schemas; open {
+		t.Errorf("got %v after cancellation, want a closed channel", schema.GetSchemaDetails())
+	}
+	if pages := fake.callCount("Range") - ranges; pages != 1 {
+		t.Errorf("fetched %d pages, want 1", pages)
+	}
+}
+
+// gatheringConverter holds the first workers conversions until all of them
+// are in flight at once, proving they run concurrently.
+type gatheringConverter struct {
+	defaultYAMLConverter
+	workers int
+	mu      sync.Mutex
+	running int
+	peak    int
+	allIn   chan struct{}
+}
+
+func (converter *gatheringConverter) JSONToYAML(data []byte) ([]byte, error) {
+	converter.mu.Lock()
+	converter.running++
+	converter.peak = max(converter.peak, converter.running)
+	if converter.running == converter.workers {
+		select {
+		case <-converter.allIn:
+		default:
+			close(converter.allIn)
+		}
+	}
+	converter.mu.Unlock()
+	select {
+	case <-converter.allIn:
+	case <-time.After(5 * time.Second):
+	}
+	defer func() {
+		converter.mu.Lock()
+		converter.running--
+		converter.mu.Unlock()
+	}()
+	return converter.defaultYAMLConverter.JSONToYAML(data)
+}
+
+func TestStreamAllConcurrentlyEmitsEachSchemaOnce(t *testing.T) {
+	const workers = 4
+	converter := &gatheringConverter{workers: workers, allIn: make(chan struct{})}
+	repo, _ := newTestRepo(t, WithIteratorPageSize(3), WithStreamConcurrency(workers), WithYAMLConverter(converter))
+	want := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("org/ns/name/v1.%d.0", i)
+		mustSave(t, repo, key)
+		want[key] = true
+	}
+
+	schemas, errs := repo.StreamAll(context.Background(), "org/ns/name/")
+	seen := make(map[string]int)
+	for schema := range schemas {
+		details := schema.GetSchemaDetails()
+		seen[fmt.Sprintf("org/ns/name/%s", details.GetVersion())]++
+		if schema.GetSchemaData().GetSchema() != testSchema {
+			t.Errorf("%s: got body %q", details.GetVersion(), schema.GetSchemaData().GetSchema())
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	for key := range want {
+		if seen[key] != 1 {
+			t.Errorf("%s emitted %d times, want once", key, seen[key])
+		}
+	}
+	if len(seen) != len(want) {
+		t.Errorf("emitted %d distinct schemas, want %d", len(seen), len(want))
+	}
+	if converter.peak != workers {
+		t.Errorf("at most %d entries were decoded at once, want %d", converter.peak, workers)
+	}
+}
+
+func TestStreamAllConcurrentlySurfacesDecodeErrors(t *testing.T) {
+	repo, fake := newTestRepo(t, WithIteratorPageSize(3), WithStreamConcurrency(4))
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0")
+	fake.put("org/ns/name/v1.1.5", "not a schema")
+
+	schemas, errs := repo.StreamAll(context.Background(), "org/ns/name/")
+	for range schemas {
+	}
+	if err := <-errs; err == nil {
+		t.Errorf("an undecodable entry did not fail the stream")
+	}
+	if _, open := <-errs; open {
+		t.Errorf("error channel left open")
+	}
+}
+
+func BenchmarkStreamAll(b *testing.B) {
+	// A schema large enough that converting it dominates the per-entry work.
+	var schema strings.Builder
+	schema.WriteString("type: object\nproperties:\n")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&schema, "  field%d:\n    type: integer\n    minimum: %d\n    description: field number %d\n", i, i, i)
+	}
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			repo, _ := newTestRepo(b, WithStreamConcurrency(workers))
+			for i := 0; i < 100; i++ {
+				if err := repo.SaveConfigSchema(context.Background(), fmt.Sprintf("org/ns/name/v1.%d.0", i), schema.String()); err != nil {
+					b.Fatalf("SaveConfigSchema: %v", err)
+				}
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				schemas, errs := repo.StreamAll(context.Background(), "org/ns/name/")
+				count := 0
+				for range schemas {
+					count++
+				}
+				if err := <-errs; err != nil || count != 100 {
+					b.Fatalf("streamed %d schemas, %v", count, err)
+				}
+			}
+		})
+	}
+}
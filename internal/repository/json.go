@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+// GetConfigSchemaJSON returns the schema under key as compact JSON with
+// object keys in sorted order, skipping the conversion to YAML.
+func (repo *EtcdRepository) GetConfigSchemaJSON(ctx context.Context, key string) ([]byte, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetConfigSchemaJSON")
+	defer span.End()
+
+	return repo.getSchemaJSON(ctx, key)
+}
+
+// GetConfigSchemaPrettyJSON is GetConfigSchemaJSON indented by two spaces,
+// for human inspection and diffs.
+func (repo *EtcdRepository) GetConfigSchemaPrettyJSON(ctx context.Context, key string) ([]byte, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetConfigSchemaPrettyJSON")
+	defer span.End()
+
+	schemaJson, err := repo.getSchemaJSON(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, schemaJson, "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+func (repo *EtcdRepository) getSchemaJSON(ctx context.Context, key string) ([]byte, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, &SchemaNotFoundError{Key: key}
+	}
+	var schemaData pb.ConfigSchemaData
+	if err := repo.decodeSchemaData(res.Kvs[0].Value, &schemaData); err != nil {
+		return nil, err
+	}
+	return canonicalJSON([]byte(schemaData.GetSchema()))
+}
+
+// canonicalJSON re-encodes data with object keys sorted, so equal
+// documents always read back byte-identical regardless of how they were
+// written. Numbers are kept verbatim.
+func canonicalJSON(data []byte) ([]byte, error) {
+	var document interface{}
+	if err := decodeJSON(data, &document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(document)
+}
+
+// decodeJSON unmarshals schema and config documents, keeping numbers as
+// json.Number so they survive re-encoding without losing precision.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected data after top-level value")
+	}
+	return nil
+}
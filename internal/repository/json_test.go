@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+func TestGetConfigSchemaPrettyJSON(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+
+	compact, err := repo.GetConfigSchemaJSON(ctx, "org/ns/name/v1.0.0")
+	if err != nil {
+		t.Fatalf("GetConfigSchemaJSON: %v", err)
+	}
+	pretty, err := repo.GetConfigSchemaPrettyJSON(ctx, "org/ns/name/v1.0.0")
+	if err != nil {
+		t.Fatalf("GetConfigSchemaPrettyJSON: %v", err)
+	}
+	if bytes.Contains(compact, []byte("\n")) {
+		t.Errorf("the default JSON is not compact: %s", compact)
+	}
+	want := "{\n  \"properties\": {\n    \"port\": {\n      \"type\": \"integer\"\n    }\n  },\n  \"type\": \"object\"\n}"
+	if string(pretty) != want {
+		t.Errorf("got %s, want %s", pretty, want)
+	}
+
+	var fromCompact, fromPretty interface{}
+	if err := json.Unmarshal(compact, &fromCompact); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if err := json.Unmarshal(pretty, &fromPretty); err != nil {
+		t.Fatalf("pretty: %v", err)
+	}
+	if !reflect.DeepEqual(fromCompact, fromPretty) {
+		t.Errorf("the forms differ: %v and %v", fromCompact, fromPretty)
+	}
+
+	if _, err := repo.GetConfigSchemaPrettyJSON(ctx, "org/ns/name/v2.0.0"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing: got %v, want ErrSchemaNotFound", err)
+	}
+}
+
+func TestGetConfigSchemaJSONIsCanonical(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	if err := repo.SaveConfigSchema(ctx, "org/ns/name/v1.0.0", "type: object\nproperties:\n  b: {type: string}\n  a: {type: integer, maximum: 1.50}\n"); err != nil {
+		t.Fatalf("SaveConfigSchema: %v", err)
+	}
+	// Written around the repository, so nothing normalized it on the way in.
+	stored, err := repo.encodeSchemaData(&pb.ConfigSchemaData{
+		Schema: `{"properties":{"a":{"maximum":1.50,"type":"integer"},"b":{"type":"string"}},"type":"object"}`,
+	})
+	if err != nil {
+		t.Fatalf("encodeSchemaData: %v", err)
+	}
+	fake.put("org/ns/name/v2.0.0", string(stored))
+	stored, err = repo.encodeSchemaData(&pb.ConfigSchemaData{
+		Schema: "{\"type\": \"object\",\n \"properties\": {\"b\": {\"type\": \"string\"}, \"a\": {\"type\": \"integer\", \"maximum\": 1.50}}}",
+	})
+	if err != nil {
+		t.Fatalf("encodeSchemaData: %v", err)
+	}
+	fake.put("org/ns/name/v3.0.0", string(stored))
+
+	want := `{"properties":{"a":{"maximum":1.50,"type":"integer"},"b":{"type":"string"}},"type":"object"}`
+	for _, key := range []string{"org/ns/name/v1.0.0", "org/ns/name/v2.0.0", "org/ns/name/v3.0.0"} {
+		schemaJson, err := repo.GetConfigSchemaJSON(ctx, key)
+		if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		if string(schemaJson) != want {
+			t.Errorf("%s: got %s, want %s", key, schemaJson, want)
+		}
+	}
+}
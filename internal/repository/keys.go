@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+// KeyCodec translates between schema details and etcd keys. Encoding
+// details with an empty version must yield the prefix shared by every
+// version of that schema.
+type KeyCodec interface {
+	EncodeKey(details *pb.ConfigSchemaDetails) string
+	DecodeKey(key string) (*pb.ConfigSchemaDetails, error)
+}
+
+// DelimitedKeyCodec joins organization, namespace, schema name and version
+// with Delimiter. The version is always the last segment, so it may itself
+// contain the delimiter (e.g. "org.ns.name.v1.0.0").
+type DelimitedKeyCodec struct {
+	Delimiter string
+}
+
+var SlashKeyCodec = DelimitedKeyCodec{Delimiter: "/"}
+
+func (codec DelimitedKeyCodec) EncodeKey(details *pb.ConfigSchemaDetails) string {
+	return strings.Join([]string{
+		details.GetOrganization(),
+		details.GetNamespace(),
+		details.GetSchemaName(),
+		details.GetVersion(),
+	}, codec.Delimiter)
+}
+
+func (codec DelimitedKeyCodec) DecodeKey(key string) (*pb.ConfigSchemaDetails, error) {
+	tokens := strings.SplitN(key, codec.Delimiter, 4)
+	if len(tokens) != 4 {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidKey, key)
+	}
+	for _, token := range tokens {
+		if token == "" {
+			return nil, fmt.Errorf("%w: '%s'", ErrInvalidKey, key)
+		}
+	}
+	return &pb.ConfigSchemaDetails{
+		Organization: tokens[0],
+		Namespace:    tokens[1],
+		SchemaName:   tokens[2],
+		Version:      tokens[3],
+	}, nil
+}
+
+func (repo *EtcdRepository) getSchemaKey(org, namespace, name, version string) string {
+	return repo.codec.EncodeKey(&pb.ConfigSchemaDetails{
+		Organization: org,
+		Namespace:    namespace,
+		SchemaName:   name,
+		Version:      version,
+	})
+}
+
+func (repo *EtcdRepository) getSchemaPrefix(org, namespace, name string) string {
+	return repo.getSchemaKey(org, namespace, name, "")
+}
+
+func (repo *EtcdRepository) getSchemaDetailsFromKey(key string) (*pb.ConfigSchemaDetails, error) {
+	return repo.codec.DecodeKey(key)
+}
+
+func (repo *EtcdRepository) getOrganizationPrefix(org string) string {
+	return repo.commonKeyPrefix(
+		&pb.ConfigSchemaDetails{Organization: org, Namespace: "a"},
+		&pb.ConfigSchemaDetails{Organization: org, Namespace: "b"},
+	)
+}
+
+func (repo *EtcdRepository) getNamespacePrefix(org, namespace string) string {
+	return repo.commonKeyPrefix(
+		&pb.ConfigSchemaDetails{Organization: org, Namespace: namespace, SchemaName: "a"},
+		&pb.ConfigSchemaDetails{Organization: org, Namespace: namespace, SchemaName: "b"},
+	)
+}
+
+// commonKeyPrefix derives a prefix from the codec as the longest common
+// prefix of the keys of two details that differ only past that prefix.
+func (repo *EtcdRepository) commonKeyPrefix(a, b *pb.ConfigSchemaDetails) string {
+	keyA, keyB := repo.codec.EncodeKey(a), repo.codec.EncodeKey(b)
+	i := 0
+	for i < len(keyA) && i < len(keyB) && keyA[i] == keyB[i] {
+		i++
+	}
+	return keyA[:i]
+}
+
+// parseSchemaPrefix returns the organization, namespace and schema name of
+// prefix, which must be exactly the prefix shared by that schema's versions.
+func (repo *EtcdRepository) parseSchemaPrefix(prefix string) (*pb.ConfigSchemaDetails, error) {
+	details, err := repo.getSchemaDetailsFromKey(prefix + "_")
+	if err != nil || repo.getSchemaPrefix(details.GetOrganization(), details.GetNamespace(), details.GetSchemaName()) != prefix {
+		return nil, fmt.Errorf("%w: '%s' is not a schema prefix", ErrInvalidKey, prefix)
+	}
+	details.Version = ""
+	return details, nil
+}
+
+// prefixSegments counts the complete key segments of prefix, i.e. those
+// followed by the codec's delimiter. Codecs without a delimiter yield 0.
+func (repo *EtcdRepository) prefixSegments(prefix string) int {
+	org := "a"
+	delimiter := strings.TrimPrefix(repo.getOrganizationPrefix(org), org)
+	if delimiter == "" {
+		return 0
+	}
+	return strings.Count(prefix, delimiter)
+}
+
+// validateKey rejects keys that do not name a schema, which also keeps
+// callers from reaching reserved or foreign keys under the base prefix.
+func (repo *EtcdRepository) validateKey(key string) error {
+	if _, err := repo.getSchemaDetailsFromKey(key); err != nil {
+		if repo.basePrefix != "" {
+			return fmt.Errorf("%w (keys are relative to base prefix '%s')", err, repo.basePrefix)
+		}
+		return err
+	}
+	return nil
+}
+
+// DefaultSegmentPattern restricts organization, namespace and schema names
+// to lowercase DNS-label-like segments.
+var DefaultSegmentPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]{0,61}[a-z0-9])?$`)
+
+func (repo *EtcdRepository) validateSegments(details *pb.ConfigSchemaDetails) error {
+	segments := []struct{ name, value string }{
+		{"organization", details.GetOrganization()},
+		{"namespace", details.GetNamespace()},
+		{"schema name", details.GetSchemaName()},
+	}
+	for _, segment := range segments {
+		if !repo.segmentPattern.MatchString(segment.value) {
+			return &InvalidSegmentError{Segment: segment.name, Value: segment.value, Pattern: repo.segmentPattern.String()}
+		}
+	}
+	return nil
+}
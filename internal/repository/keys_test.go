@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDelimitedKeyCodecRoundTrip(t *testing.T) {
+	codec := DelimitedKeyCodec{Delimiter: "."}
+	details := &pb.ConfigSchemaDetails{Organization: "org", Namespace: "ns", SchemaName: "name", Version: "v1.2.3"}
+
+	key := codec.EncodeKey(details)
+	if key != "org.ns.name.v1.2.3" {
+		t.Fatalf("got key %q", key)
+	}
+	decoded, err := codec.DecodeKey(key)
+	if err != nil {
+		t.Fatalf("DecodeKey: %v", err)
+	}
+	if !proto.Equal(decoded, details) {
+		t.Errorf("got %v, want %v", decoded, details)
+	}
+	for _, key := range []string{"org.ns.name", "org..name.v1.0.0", ""} {
+		if _, err := codec.DecodeKey(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%q: got %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestRepositoryUsesKeyCodec(t *testing.T) {
+	repo, fake := newTestRepo(t, WithKeyCodec(DelimitedKeyCodec{Delimiter: "."}))
+	mustSave(t, repo, "org.ns.name.v1.0.0")
+	if fake.get("org.ns.name.v1.0.0") == nil {
+		t.Fatalf("schema not stored under the dotted key; stored keys are %v", fake.keys())
+	}
+	schemas, err := repo.Scoped("org", "ns").ListVersions(context.Background(), "name")
+	if err != nil {
+		t.Fatalf("ListVersions: %v", err)
+	}
+	if len(schemas) != 1 || schemas[0].GetSchemaDetails().GetVersion() != "v1.0.0" {
+		t.Errorf("got %v", schemas)
+	}
+}
+
+func TestReadsAndDeletesRejectKeysOutsideBasePrefix(t *testing.T) {
+	repo, fake := newTestRepo(t, WithBasePrefix("apps/quasar/"))
+	ctx := context.Background()
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	if !slices.Contains(fake.keys(), "apps/quasar/org/ns/name/v1.0.0") {
+		t.Fatalf("schema not stored under the base prefix: %v", fake.keys())
+	}
+	ranges := fake.callCount("Range")
+
+	operations := map[string]func(key string) error{
+		"GetConfigSchema": func(key string) error {
+			_, err := repo.GetConfigSchema(ctx, key)
+			return err
+		},
+		"GetRaw": func(key string) error {
+			_, err := repo.GetRaw(ctx, key)
+			return err
+		},
+		"GetConfigSchemaFull": func(key string) error {
+			_, err := repo.GetConfigSchemaFull(ctx, key)
+			return err
+		},
+		"DeleteConfigSchema": func(key string) error {
+			return repo.DeleteConfigSchema(ctx, key)
+		},
+	}
+	for name, operation := range operations {
+		for _, key := range []string{"/org/ns/name/v1.0.0", "org/ns/name", "org//name/v1.0.0"} {
+			err := operation(key)
+			if !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("%s(%q): got %v, want ErrInvalidKey", name, key, err)
+			} else if !strings.Contains(err.Error(), "apps/quasar/") {
+				t.Errorf("%s(%q): error %q does not name the base prefix", name, key, err)
+			}
+		}
+	}
+	if got := fake.callCount("Range") - ranges; got != 0 {
+		t.Errorf("invalid keys caused %d reads", got)
+	}
+	if got := fake.callCount("DeleteRange"); got != 0 {
+		t.Errorf("invalid keys caused %d deletes", got)
+	}
+
+	if _, err := repo.GetConfigSchemaFull(ctx, "org/ns/name/v1.0.0"); err != nil {
+		t.Errorf("GetConfigSchemaFull of a valid key: %v", err)
+	}
+}
+
+func TestSaveValidatesSegments(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	for _, key := range []string{"acme/payments/card-v2/v1.0.0", "a1/ns.internal/x_y/v1.0.0"} {
+		if err := repo.SaveConfigSchema(ctx, key, testSchema); err != nil {
+			t.Errorf("%s: %v", key, err)
+		}
+	}
+
+	writes := fake.callCount("Txn") + fake.callCount("Put")
+	for _, test := range []struct {
+		key, segment string
+	}{
+		{"Acme/payments/card/v1.0.0", "organization"},
+		{"acme/my payments/card/v1.0.0", "namespace"},
+		{"acme/payments/card\t/v1.0.0", "schema name"},
+		{"acme/payments/-card/v1.0.0", "schema name"},
+	} {
+		err := repo.SaveConfigSchema(ctx, test.key, testSchema)
+		var segmentErr *InvalidSegmentError
+		if !errors.As(err, &segmentErr) || !errors.Is(err, ErrInvalidSegment) {
+			t.Errorf("%q: got %v, want InvalidSegmentError", test.key, err)
+		} else if segmentErr.Segment != test.segment {
+			t.Errorf("%q: rejected segment %s, want %s", test.key, segmentErr.Segment, test.segment)
+		}
+	}
+	if got := fake.callCount("Txn") + fake.callCount("Put") - writes; got != 0 {
+		t.Errorf("invalid segments caused %d writes", got)
+	}
+
+	custom, _ := newTestRepo(t, WithSegmentPattern(regexp.MustCompile(`^[A-Za-z]+$`)))
+	if err := custom.SaveConfigSchema(ctx, "Acme/Payments/Card/v1.0.0", testSchema); err != nil {
+		t.Errorf("custom pattern: %v", err)
+	}
+	if err := custom.SaveConfigSchema(ctx, "acme/payments/card-v2/v1.0.0", testSchema); !errors.Is(err, ErrInvalidSegment) {
+		t.Errorf("custom pattern: got %v, want ErrInvalidSegment", err)
+	}
+}
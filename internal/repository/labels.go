@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// labelSelector is a comma separated conjunction of requirements in the
+// form "key=value", "key!=value", "key" (present) or "!key" (absent).
+type labelSelector []labelRequirement
+
+type labelRequirement struct {
+	key      string
+	value    string
+	operator string
+}
+
+func parseLabelSelector(selector string) (labelSelector, error) {
+	var parsed labelSelector
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		var requirement labelRequirement
+		if key, value, found := strings.Cut(term, "!="); found {
+			requirement = labelRequirement{key: key, value: value, operator: "!="}
+		} else if key, value, found := strings.Cut(term, "=="); found {
+			requirement = labelRequirement{key: key, value: value, operator: "="}
+		} else if key, value, found := strings.Cut(term, "="); found {
+			requirement = labelRequirement{key: key, value: value, operator: "="}
+		} else if strings.HasPrefix(term, "!") {
+			requirement = labelRequirement{key: term[1:], operator: "!"}
+		} else {
+			requirement = labelRequirement{key: term, operator: "exists"}
+		}
+		requirement.key = strings.TrimSpace(requirement.key)
+		requirement.value = strings.TrimSpace(requirement.value)
+		if requirement.key == "" {
+			return nil, fmt.Errorf("%w: '%s'", ErrInvalidLabelSelector, selector)
+		}
+		parsed = append(parsed, requirement)
+	}
+	return parsed, nil
+}
+
+func (selector labelSelector) matches(labels map[string]string) bool {
+	for _, requirement := range selector {
+		value, ok := labels[requirement.key]
+		switch requirement.operator {
+		case "=":
+			if !ok || value != requirement.value {
+				return false
+			}
+		case "!=":
+			if ok && value == requirement.value {
+				return false
+			}
+		case "!":
+			if ok {
+				return false
+			}
+		case "exists":
+			if !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// maxTxnOps matches etcd's default limit on operations per transaction.
+const maxTxnOps = 128
+
+// AddLabelToPrefix sets the label name=value on every schema under prefix
+// that does not carry it yet and returns how many were updated. Schemas are
+// written back in transactions of up to maxTxnOps, each guarded on the
+// revisions read; a batch that loses a race is retried schema by schema.
+// TTLs are kept.
+func (repo *EtcdRepository) AddLabelToPrefix(ctx context.Context, prefix, name, value string) (int, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.AddLabelToPrefix")
+	defer span.End()
+
+	if repo.readOnly {
+		return 0, ErrReadOnly
+	}
+	if prefix == "" {
+		return 0, ErrEmptyPrefix
+	}
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+	var cmps []clientv3.Cmp
+	var ops []clientv3.Op
+	var keys []string
+	growth := make(map[string]int64)
+	updated := 0
+	flush := func() error {
+		if len(ops) == 0 {
+			return nil
+		}
+		for _, org := range slices.Sorted(maps.Keys(growth)) {
+			if err := repo.checkOrgQuota(ctx, org, growth[org]); err != nil {
+				return err
+			}
+		}
+		txnRes, err := repo.kv.Txn(ctx).If(cmps...).Then(ops...).Commit()
+		if err != nil {
+			return err
+		}
+		if txnRes.Succeeded {
+			updated += len(ops)
+		} else {
+			for _, key := range keys {
+				changed, err := repo.addLabel(ctx, key, name, value)
+				if err != nil {
+					return err
+				}
+				if changed {
+					updated++
+				}
+			}
+		}
+		cmps, ops, keys = nil, nil, nil
+		clear(growth)
+		return nil
+	}
+	for _, kv := range res.Kvs {
+		key := string(kv.Key)
+		if repo.isReservedKey(key) {
+			continue
+		}
+		var schemaData pb.ConfigSchemaData
+		if err := repo.decodeSchemaData(kv.Value, &schemaData); err != nil {
+			return updated, err
+		}
+		if !setLabel(&schemaData, name, value) {
+			continue
+		}
+		serializedData, err := repo.encodeSchemaData(&schemaData)
+		if err != nil {
+			return updated, err
+		}
+		if repo.orgQuota > 0 && len(serializedData) > len(kv.Value) {
+			schemaDetails, err := repo.getSchemaDetailsFromKey(key)
+			if err != nil {
+				return updated, err
+			}
+			growth[schemaDetails.GetOrganization()] += int64(len(serializedData) - len(kv.Value))
+		}
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(key), "=", kv.ModRevision))
+		ops = append(ops, clientv3.OpPut(key, string(serializedData), clientv3.WithIgnoreLease()))
+		keys = append(keys, key)
+		if len(ops) == maxTxnOps {
+			if err := flush(); err != nil {
+				return updated, err
+			}
+		}
+	}
+	return updated, flush()
+}
+
+// addLabel sets the label on the schema under key unless it already has
+// it; deleted schemas are skipped.
+func (repo *EtcdRepository) addLabel(ctx context.Context, key, name, value string) (bool, error) {
+	changed := false
+	err := repo.ModifyConfigSchema(ctx, key, func(current *pb.ConfigSchemaData) error {
+		changed = setLabel(current, name, value)
+		return nil
+	})
+	if errors.Is(err, ErrSchemaNotFound) {
+		return false, nil
+	}
+	return changed, err
+}
+
+func setLabel(schemaData *pb.ConfigSchemaData, name, value string) bool {
+	if current, ok := schemaData.GetLabels()[name]; ok && current == value {
+		return false
+	}
+	if schemaData.Labels == nil {
+		schemaData.Labels = make(map[string]string)
+	}
+	schemaData.Labels[name] = value
+	return true
+}
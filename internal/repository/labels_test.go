@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"testing"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+func TestAddLabelToPrefix(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	// More schemas than fit in one transaction.
+	const schemas = maxTxnOps + 5
+	for i := 0; i < schemas; i++ {
+		mustSave(t, repo, fmt.Sprintf("org/ns/name/v1.%d.0", i), WithLabels(map[string]string{"team": "payments"}))
+	}
+	mustSave(t, repo, "org/ns/done/v1.0.0", WithLabels(map[string]string{"reviewed": "true"}))
+	mustSave(t, repo, "org/other/name/v1.0.0")
+	txns := fake.callCount("Txn")
+
+	updated, err := repo.AddLabelToPrefix(ctx, "org/ns/", "reviewed", "true")
+	if err != nil {
+		t.Fatalf("AddLabelToPrefix: %v", err)
+	}
+	if updated != schemas {
+		t.Errorf("updated %d schemas, want %d", updated, schemas)
+	}
+	if got := fake.callCount("Txn") - txns; got != 2 {
+		t.Errorf("wrote in %d transactions, want 2", got)
+	}
+	for i := 0; i < schemas; i++ {
+		key := fmt.Sprintf("org/ns/name/v1.%d.0", i)
+		schemaData, err := repo.GetConfigSchema(ctx, key)
+		if err != nil {
+			t.Fatalf("GetConfigSchema(%s): %v", key, err)
+		}
+		if want := map[string]string{"team": "payments", "reviewed": "true"}; !maps.Equal(schemaData.GetLabels(), want) {
+			t.Fatalf("%s: got labels %v, want %v", key, schemaData.GetLabels(), want)
+		}
+	}
+	if schemaData, err := repo.GetConfigSchema(ctx, "org/other/name/v1.0.0"); err != nil || len(schemaData.GetLabels()) != 0 {
+		t.Errorf("outside the prefix: got labels %v, %v", schemaData.GetLabels(), err)
+	}
+
+	// Everything is labelled already, so nothing is written.
+	txns = fake.callCount("Txn")
+	if updated, err := repo.AddLabelToPrefix(ctx, "org/ns/", "reviewed", "true"); err != nil || updated != 0 {
+		t.Errorf("second run: got %d, %v, want 0", updated, err)
+	}
+	if got := fake.callCount("Txn") - txns; got != 0 {
+		t.Errorf("second run wrote in %d transactions", got)
+	}
+
+	if _, err := repo.AddLabelToPrefix(ctx, "", "reviewed", "true"); !errors.Is(err, ErrEmptyPrefix) {
+		t.Errorf("empty prefix: got %v, want ErrEmptyPrefix", err)
+	}
+}
+
+func TestAddLabelToPrefixRetriesLostBatch(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0", "v1.2.0")
+
+	// Another writer changes one schema after it was read, so the batch's
+	// guard fails and each schema is labelled on its own.
+	arrived, release := fake.hold("Txn")
+	type result struct {
+		updated int
+		err     error
+	}
+	results := make(chan result, 1)
+	go func() {
+		updated, err := repo.AddLabelToPrefix(ctx, "org/ns/", "reviewed", "true")
+		results <- result{updated, err}
+	}()
+	<-arrived
+	stored, err := repo.encodeSchemaData(&pb.ConfigSchemaData{Schema: `{"type":"string"}`, Author: "ana"})
+	if err != nil {
+		t.Fatalf("encodeSchemaData: %v", err)
+	}
+	fake.put("org/ns/name/v1.1.0", string(stored))
+	release()
+
+	got := <-results
+	if got.err != nil || got.updated != 3 {
+		t.Fatalf("got %d, %v, want 3", got.updated, got.err)
+	}
+	for _, version := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		schemaData, err := repo.GetConfigSchema(ctx, "org/ns/name/"+version)
+		if err != nil || schemaData.GetLabels()["reviewed"] != "true" {
+			t.Errorf("%s: got labels %v, %v", version, schemaData.GetLabels(), err)
+		}
+	}
+	if schemaData, _ := repo.GetConfigSchema(ctx, "org/ns/name/v1.1.0"); schemaData.GetAuthor() != "ana" {
+		t.Errorf("the concurrent write was lost: got %v", schemaData)
+	}
+}
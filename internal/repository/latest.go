@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// latestPointerVersion names the pointer key holding the latest version of
+// a schema, kept up to date by every write so the latest version can be
+// read without scanning all versions.
+const latestPointerVersion = "_latest"
+
+// latestPointerUpdate returns the guard and operations a save of details
+// must commit with to advance the latest pointer, if its version is newer
+// than the current latest. A missing pointer, e.g. for schemas written
+// before pointers existed, is seeded from the stored versions.
+func (repo *EtcdRepository) latestPointerUpdate(ctx context.Context, details *pb.ConfigSchemaDetails) ([]clientv3.Cmp, []clientv3.Op, error) {
+	org, namespace, name, version := details.GetOrganization(), details.GetNamespace(), details.GetSchemaName(), details.GetVersion()
+	pointerKey := repo.getSchemaKey(org, namespace, name, latestPointerVersion)
+	res, err := repo.kv.Get(ctx, pointerKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(res.Kvs) == 0 {
+		latest, err := repo.GetLatestVersionByPrefix(ctx, repo.getSchemaPrefix(org, namespace, name))
+		if err != nil {
+			return nil, nil, err
+		}
+		if latest == "" || repo.comparator.Compare(version, latest) == 1 {
+			latest = version
+		}
+		return []clientv3.Cmp{clientv3.Compare(clientv3.CreateRevision(pointerKey), "=", 0)},
+			[]clientv3.Op{clientv3.OpPut(pointerKey, latest)}, nil
+	}
+	guard := []clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(pointerKey), "=", res.Kvs[0].ModRevision)}
+	if repo.comparator.Compare(version, string(res.Kvs[0].Value)) != 1 {
+		return guard, nil, nil
+	}
+	return guard, []clientv3.Op{clientv3.OpPut(pointerKey, version)}, nil
+}
+
+// refreshLatestPointer recomputes the latest pointer of a schema from its
+// stored versions, removing it when none are left. It is used after writes
+// that can lower the latest version, such as deletes.
+func (repo *EtcdRepository) refreshLatestPointer(ctx context.Context, org, namespace, name string) error {
+	prefix := repo.getSchemaPrefix(org, namespace, name)
+	pointerKey := repo.getSchemaKey(org, namespace, name, latestPointerVersion)
+	for attempt := 0; attempt < maxModifyAttempts; attempt++ {
+		details, revision, err := repo.listSchemaDetails(ctx, prefix)
+		if err != nil {
+			return err
+		}
+		op := clientv3.OpDelete(pointerKey)
+		if len(details) > 0 {
+			op = clientv3.OpPut(pointerKey, details[len(details)-1].GetVersion())
+		}
+		res, err := repo.kv.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(prefix), "<", revision+1).WithPrefix()).
+			Then(op).
+			Commit()
+		if err != nil {
+			return err
+		}
+		if res.Succeeded {
+			return nil
+		}
+	}
+	return ErrConcurrentModification
+}
+
+// refreshLatestPointers refreshes the pointers of every schema among keys.
+func (repo *EtcdRepository) refreshLatestPointers(ctx context.Context, keys []string) error {
+	refreshed := make(map[string]bool)
+	for _, key := range keys {
+		details, err := repo.getSchemaDetailsFromKey(key)
+		if err != nil || isReservedVersion(details.GetVersion()) {
+			continue
+		}
+		prefix := repo.getSchemaPrefix(details.GetOrganization(), details.GetNamespace(), details.GetSchemaName())
+		if refreshed[prefix] {
+			continue
+		}
+		refreshed[prefix] = true
+		if err := repo.refreshLatestPointer(ctx, details.GetOrganization(), details.GetNamespace(), details.GetSchemaName()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
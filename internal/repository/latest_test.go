@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestGetLatestConfigSchema(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	if _, err := repo.GetLatestConfigSchema(ctx, "org", "ns", "name"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("no versions: got %v, want ErrSchemaNotFound", err)
+	}
+
+	saveVersions(t, repo, "org/ns/name/", "v1.2.0", "v1.10.0", "v1.9.0")
+	latest, err := repo.GetLatestConfigSchema(ctx, "org", "ns", "name")
+	if err != nil {
+		t.Fatalf("GetLatestConfigSchema: %v", err)
+	}
+	if version := latest.GetSchemaDetails().GetVersion(); version != "v1.10.0" {
+		t.Errorf("got %s, want v1.10.0", version)
+	}
+	if latest.GetSchemaData().GetSchema() != testSchema {
+		t.Errorf("got body %q", latest.GetSchemaData().GetSchema())
+	}
+}
+
+func TestSaveRejectsLatestAsVersion(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	err := repo.SaveConfigSchema(context.Background(), "org/ns/name/"+LatestVersion, testSchema)
+	if !errors.Is(err, ErrReservedVersion) {
+		t.Errorf("got %v, want ErrReservedVersion", err)
+	}
+	if keys := fake.keys(); len(keys) != 0 {
+		t.Errorf("rejected save wrote %v", keys)
+	}
+}
+
+func TestSaveIfLatest(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+
+	if err := repo.SaveIfLatest(ctx, "org", "ns", "name", "v1.0.0", testSchema, ""); err != nil {
+		t.Fatalf("first version: %v", err)
+	}
+	if err := repo.SaveIfLatest(ctx, "org", "ns", "name", "v1.1.0", testSchema, "v1.0.0"); err != nil {
+		t.Fatalf("matching latest: %v", err)
+	}
+
+	err := repo.SaveIfLatest(ctx, "org", "ns", "name", "v1.2.0", testSchema, "v1.0.0")
+	var mismatch *LatestMismatchError
+	if !errors.As(err, &mismatch) || !errors.Is(err, ErrLatestMismatch) {
+		t.Fatalf("stale latest: got %v, want LatestMismatchError", err)
+	}
+	if mismatch.Expected != "v1.0.0" || mismatch.Actual != "v1.1.0" {
+		t.Errorf("got %+v", mismatch)
+	}
+	if fake.get("org/ns/name/v1.2.0") != nil {
+		t.Errorf("the mismatched save was written")
+	}
+
+	latest, err := repo.GetLatestConfigSchema(ctx, "org", "ns", "name")
+	if err != nil || latest.GetSchemaDetails().GetVersion() != "v1.1.0" {
+		t.Errorf("got latest %v, %v, want v1.1.0", latest.GetSchemaDetails(), err)
+	}
+}
+
+func TestSaveIfLatestHonorsSaveOptions(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	saveVersions(t, repo, "org/ns/name/", "v2.0.0")
+
+	err := repo.SaveIfLatest(ctx, "org", "ns", "name", "v1.5.0", testSchema, "v2.0.0", WithMonotonicVersions())
+	if !errors.Is(err, ErrVersionNotGreater) {
+		t.Errorf("monotonic backfill: got %v, want ErrVersionNotGreater", err)
+	}
+
+	// A backfill is allowed without the option but leaves the latest alone.
+	if err := repo.SaveIfLatest(ctx, "org", "ns", "name", "v1.5.0", testSchema, "v2.0.0"); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if latest, err := repo.GetLatestConfigSchema(ctx, "org", "ns", "name"); err != nil || latest.GetSchemaDetails().GetVersion() != "v2.0.0" {
+		t.Errorf("backfill moved the latest to %v, %v", latest.GetSchemaDetails(), err)
+	}
+
+	if err := repo.SaveIfLatest(ctx, "org", "ns", "name", "v3.0.0", testSchema, "v2.0.0", WithTTL(time.Minute)); err != nil {
+		t.Fatalf("with TTL: %v", err)
+	}
+	if kv := fake.get("org/ns/name/v3.0.0"); kv == nil || kv.Lease == 0 {
+		t.Errorf("the schema saved with a TTL has no lease: %v", kv)
+	}
+}
+
+func TestGetLatestVersions(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	saveVersions(t, repo, "org/ns/a/", "v1.0.0", "v1.10.0", "v1.9.0")
+	saveVersions(t, repo, "org/ns/b/", "v0.1.0")
+	saveVersions(t, repo, "org/other/c/", "v2.0.0")
+
+	latest, err := repo.GetLatestVersions(context.Background(), []string{"org/ns/a/", "org/ns/b/", "org/ns/empty/"})
+	if err != nil {
+		t.Fatalf("GetLatestVersions: %v", err)
+	}
+	want := map[string]string{"org/ns/a/": "v1.10.0", "org/ns/b/": "v0.1.0", "org/ns/empty/": ""}
+	if !maps.Equal(latest, want) {
+		t.Errorf("got %v, want %v", latest, want)
+	}
+
+	// Nested prefixes share the scan of the outer one.
+	ranges := fake.callCount("Range")
+	latest, err = repo.GetLatestVersions(context.Background(), []string{"org/ns/a/", "org/", "org/ns/"})
+	if err != nil {
+		t.Fatalf("GetLatestVersions: %v", err)
+	}
+	want = map[string]string{"org/ns/a/": "v1.10.0", "org/": "v2.0.0", "org/ns/": "v1.10.0"}
+	if !maps.Equal(latest, want) {
+		t.Errorf("nested: got %v, want %v", latest, want)
+	}
+	if scans := fake.callCount("Range") - ranges; scans != 1 {
+		t.Errorf("nested prefixes took %d scans, want 1", scans)
+	}
+}
+
+func TestLatestPointer(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	pointerKey := "org/ns/name/" + latestPointerVersion
+	pointer := func() string {
+		t.Helper()
+		if kv := fake.get(pointerKey); kv != nil {
+			return string(kv.Value)
+		}
+		return ""
+	}
+
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	mustSave(t, repo, "org/ns/name/v1.10.0")
+	if got := pointer(); got != "v1.10.0" {
+		t.Errorf("after a higher save: pointer %q, want v1.10.0", got)
+	}
+	written := fake.get(pointerKey).ModRevision
+	mustSave(t, repo, "org/ns/name/v1.9.0")
+	if got := pointer(); got != "v1.10.0" || fake.get(pointerKey).ModRevision != written {
+		t.Errorf("after a lower save: pointer %q rewritten, want v1.10.0 untouched", got)
+	}
+
+	// Reads follow the pointer with two single-key gets, not a scan.
+	ranges := fake.callCount("Range")
+	latest, err := repo.GetLatestConfigSchema(ctx, "org", "ns", "name")
+	if err != nil || latest.GetSchemaDetails().GetVersion() != "v1.10.0" {
+		t.Errorf("GetLatestConfigSchema: got %v, %v", latest.GetSchemaDetails(), err)
+	}
+	if got := fake.callCount("Range") - ranges; got != 2 || fake.lastRange().GetRangeEnd() != nil {
+		t.Errorf("read the latest with %d ranges, last %v", got, fake.lastRange())
+	}
+
+	if err := repo.DeleteConfigSchema(ctx, "org/ns/name/v1.10.0"); err != nil {
+		t.Fatalf("DeleteConfigSchema: %v", err)
+	}
+	if got := pointer(); got != "v1.9.0" {
+		t.Errorf("after deleting the latest: pointer %q, want v1.9.0", got)
+	}
+	for _, version := range []string{"v1.0.0", "v1.9.0"} {
+		if err := repo.DeleteConfigSchema(ctx, "org/ns/name/"+version); err != nil {
+			t.Fatalf("DeleteConfigSchema: %v", err)
+		}
+	}
+	if fake.get(pointerKey) != nil {
+		t.Errorf("the pointer outlived every version: %q", pointer())
+	}
+
+	// Schemas saved before pointers existed fall back to a scan.
+	saveVersions(t, repo, "org/ns/legacy/", "v2.0.0", "v1.0.0")
+	if _, err := repo.etcdClient().Delete(ctx, "org/ns/legacy/"+latestPointerVersion); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	latest, err = repo.GetLatestConfigSchema(ctx, "org", "ns", "legacy")
+	if err != nil || latest.GetSchemaDetails().GetVersion() != "v2.0.0" {
+		t.Errorf("without a pointer: got %v, %v", latest.GetSchemaDetails(), err)
+	}
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// Defragment releases the space freed by compaction on every configured
+// endpoint, one endpoint at a time. Defragmentation blocks reads and writes
+// on the endpoint while it runs, so schedule it outside peak traffic. The
+// operation is bounded only by ctx, not the repository timeout, because
+// large databases can take a while to defragment.
+func (repo *EtcdRepository) Defragment(ctx context.Context) error {
+	ctx, span := repo.startSpan(ctx, "Repository.Defragment")
+	defer span.End()
+
+	cli := repo.etcdClient()
+	var errs []error
+	for _, endpoint := range cli.Endpoints() {
+		if _, err := cli.Defragment(ctx, endpoint); err != nil {
+			errs = append(errs, fmt.Errorf("endpoint %s: %w", endpoint, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// probePrefix holds the short-lived keys CheckWritable writes.
+const probePrefix = "_probe/"
+
+// CheckWritable verifies that the repository may write to etcd by putting
+// and deleting a uniquely named probe key. A write rejected by etcd RBAC
+// fails with ErrNotWritable. Under WithTenant the probe key is one of the
+// tenant's organization. The probe key is removed before returning; if
+// that delete fails, the error names the key left behind.
+func (repo *EtcdRepository) CheckWritable(ctx context.Context) error {
+	ctx, span := repo.startSpan(ctx, "Repository.CheckWritable")
+	defer span.End()
+
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	key := probePrefix + newCorrelationID()
+	if tenant := Tenant(ctx); tenant != "" {
+		key = internalKey(probePrefix, tenant, newCorrelationID())
+	}
+	if _, err := repo.kv.Put(ctx, key, ""); err != nil {
+		if errors.Is(err, rpctypes.ErrPermissionDenied) {
+			return fmt.Errorf("%w: %v", ErrNotWritable, err)
+		}
+		return err
+	}
+	if _, err := repo.kv.Delete(ctx, key); err != nil {
+		return fmt.Errorf("removing probe key '%s': %w", key, err)
+	}
+	return nil
+}
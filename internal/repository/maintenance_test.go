@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDefragmentEveryEndpoint(t *testing.T) {
+	first, second := newFakeEtcd(t), newFakeEtcd(t)
+	repo, err := NewClient(WithLogger(slog.New(slog.DiscardHandler)), func(repo *EtcdRepository) {
+		repo.config.Endpoints = []string{first.addr, second.addr}
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(repo.Close)
+
+	if err := repo.Defragment(context.Background()); err != nil {
+		t.Fatalf("Defragment: %v", err)
+	}
+	if first.defragmentCount() != 1 || second.defragmentCount() != 1 {
+		t.Errorf("defragmented %d and %d times, want once each", first.defragmentCount(), second.defragmentCount())
+	}
+
+	first.failNext("Defragment", status.Error(codes.Internal, "disk full"))
+	err = repo.Defragment(context.Background())
+	if err == nil || !strings.Contains(err.Error(), first.addr) || strings.Contains(err.Error(), second.addr) {
+		t.Errorf("got %v, want an error naming only %s", err, first.addr)
+	}
+	if second.defragmentCount() != 2 {
+		t.Errorf("a failing endpoint stopped the others from being defragmented")
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	if err := repo.CheckWritable(ctx); err != nil {
+		t.Fatalf("CheckWritable: %v", err)
+	}
+	if fake.callCount("Put") != 1 || fake.callCount("DeleteRange") != 1 || len(fake.keys()) != 0 {
+		t.Errorf("the probe was not written and removed: %d puts, %d deletes, keys %v", fake.callCount("Put"), fake.callCount("DeleteRange"), fake.keys())
+	}
+
+	// A read-only etcd user is denied the put.
+	fake.failNext("Put", rpctypes.ErrGRPCPermissionDenied)
+	if err := repo.CheckWritable(ctx); !errors.Is(err, ErrNotWritable) {
+		t.Errorf("denied: got %v, want ErrNotWritable", err)
+	}
+	if fake.callCount("DeleteRange") != 1 || len(fake.keys()) != 0 {
+		t.Errorf("denied: tried to clean up %v", fake.keys())
+	}
+
+	// Under a tenant the probe lives in the tenant's organization; a failed
+	// cleanup names the key left behind.
+	fake.failNext("DeleteRange", status.Error(codes.Internal, "injected failure"))
+	err := repo.CheckWritable(WithTenant(ctx, "acme"))
+	keys := fake.keys()
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], internalKey(probePrefix, "acme", "")) {
+		t.Fatalf("got probe keys %v, want one under the tenant", keys)
+	}
+	if err == nil || !strings.Contains(err.Error(), keys[0]) {
+		t.Errorf("failed cleanup: got %v, want it to name %s", err, keys[0])
+	}
+}
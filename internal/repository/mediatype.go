@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+const (
+	MediaTypeSchemaJSON = "application/schema+json"
+	MediaTypeYAML       = "application/yaml"
+)
+
+// SchemaMediaType reports the media type of the schema body as stored
+// under key: MediaTypeSchemaJSON for bodies written with StorageJSON and
+// MediaTypeYAML for bodies written with StorageYAML, as recorded when the
+// schema was last written. Schemas saved before a switch of the storage
+// format keep reporting the format they were written in. Records written
+// before the media type was recorded are told apart by whether their body
+// parses as JSON.
+func (repo *EtcdRepository) SchemaMediaType(ctx context.Context, key string) (string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.SchemaMediaType")
+	defer span.End()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if len(res.Kvs) == 0 {
+		return "", &SchemaNotFoundError{Key: key}
+	}
+	var schemaData pb.ConfigSchemaData
+	if err := repo.unmarshal(res.Kvs[0].Value, &schemaData); err != nil {
+		return "", err
+	}
+	if mediaType := schemaData.GetMediaType(); mediaType != "" {
+		return mediaType, nil
+	}
+	if json.Valid([]byte(schemaData.GetSchema())) {
+		return MediaTypeSchemaJSON, nil
+	}
+	return MediaTypeYAML, nil
+}
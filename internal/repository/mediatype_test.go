@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+func TestSchemaMediaType(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		format StorageFormat
+		want   string
+	}{
+		{StorageJSON, MediaTypeSchemaJSON},
+		{StorageYAML, MediaTypeYAML},
+	} {
+		repo, _ := newTestRepo(t, WithStorageFormat(test.format))
+		mustSave(t, repo, "org/ns/name/v1.0.0")
+		if mediaType, err := repo.SchemaMediaType(ctx, "org/ns/name/v1.0.0"); err != nil || mediaType != test.want {
+			t.Errorf("format %d: got %q, %v, want %q", test.format, mediaType, err, test.want)
+		}
+		if _, err := repo.SchemaMediaType(ctx, "org/ns/name/v2.0.0"); !errors.Is(err, ErrSchemaNotFound) {
+			t.Errorf("format %d: missing: got %v, want ErrSchemaNotFound", test.format, err)
+		}
+	}
+}
+
+func TestSchemaMediaTypeIsTheStoredFormat(t *testing.T) {
+	ctx := context.Background()
+	yamlRepo, fake := newTestRepo(t, WithStorageFormat(StorageYAML))
+	jsonRepo, err := NewClient(fake.endpoint(), WithLogger(slog.New(slog.DiscardHandler)))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer jsonRepo.Close()
+
+	// Stored as YAML, these bodies are also valid JSON.
+	for i, schema := range []string{"{}", "true"} {
+		key := fmt.Sprintf("org/ns/yaml/v1.%d.0", i)
+		if err := yamlRepo.SaveConfigSchema(ctx, key, schema); err != nil {
+			t.Fatalf("SaveConfigSchema(%s): %v", key, err)
+		}
+		if !json.Valid([]byte(storedData(t, fake, key).GetSchema())) {
+			t.Fatalf("%s: stored body %q is not valid JSON", key, storedData(t, fake, key).GetSchema())
+		}
+		for _, repo := range []*EtcdRepository{yamlRepo, jsonRepo} {
+			if mediaType, err := repo.SchemaMediaType(ctx, key); err != nil || mediaType != MediaTypeYAML {
+				t.Errorf("%s: got %q, %v, want %q", key, mediaType, err, MediaTypeYAML)
+			}
+		}
+	}
+
+	mustSave(t, jsonRepo, "org/ns/json/v1.0.0")
+	if mediaType, err := yamlRepo.SchemaMediaType(ctx, "org/ns/json/v1.0.0"); err != nil || mediaType != MediaTypeSchemaJSON {
+		t.Errorf("saved as JSON: got %q, %v, want %q", mediaType, err, MediaTypeSchemaJSON)
+	}
+	if schemaData, err := yamlRepo.GetConfigSchema(ctx, "org/ns/json/v1.0.0"); err != nil || schemaData.GetMediaType() != "" {
+		t.Errorf("the stored media type was handed to a reader: %q, %v", schemaData.GetMediaType(), err)
+	}
+
+	// Records written before the media type was recorded.
+	for body, want := range map[string]string{`{"type":"object"}`: MediaTypeSchemaJSON, "type: object\n": MediaTypeYAML} {
+		value, err := defaultMarshaler(&pb.ConfigSchemaData{Schema: body})
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		fake.put("org/ns/legacy/v1.0.0", string(value))
+		if mediaType, err := yamlRepo.SchemaMediaType(ctx, "org/ns/legacy/v1.0.0"); err != nil || mediaType != want {
+			t.Errorf("legacy %q: got %q, %v, want %q", body, mediaType, err, want)
+		}
+	}
+}
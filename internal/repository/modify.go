@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var maxModifyAttempts = 3
+
+// ModifyConfigSchema reads the schema under key, lets fn mutate it and
+// writes the result back only if the key has not changed in the meantime.
+// On a conflicting write it starts over with the new value, at most
+// maxModifyAttempts times, so fn must be safe to call repeatedly. The
+// schema passed to fn holds the stored JSON, not YAML. Any TTL of the
+// schema is kept.
+func (repo *EtcdRepository) ModifyConfigSchema(ctx context.Context, key string, fn func(current *pb.ConfigSchemaData) error) error {
+	ctx, span := repo.startSpan(ctx, "Repository.ModifyConfigSchema")
+	defer span.End()
+
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	for attempt := 0; attempt < maxModifyAttempts; attempt++ {
+		res, err := repo.kv.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if len(res.Kvs) == 0 {
+			return &SchemaNotFoundError{Key: key}
+		}
+		var schemaData pb.ConfigSchemaData
+		if err := repo.decodeSchemaData(res.Kvs[0].Value, &schemaData); err != nil {
+			return err
+		}
+		previousID := schemaID(schemaData.GetSchema())
+		if err := fn(&schemaData); err != nil {
+			return err
+		}
+		serializedData, err := repo.encodeSchemaData(&schemaData)
+		if err != nil {
+			return err
+		}
+		if err := repo.checkRewriteQuota(ctx, key, len(res.Kvs[0].Value), len(serializedData)); err != nil {
+			return err
+		}
+		txnRes, err := repo.kv.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", res.Kvs[0].ModRevision)).
+			Then(append([]clientv3.Op{clientv3.OpPut(key, string(serializedData), clientv3.WithIgnoreLease())}, repo.idIndexOps(key, schemaData.GetSchema())...)...).
+			Commit()
+		if err != nil {
+			return err
+		}
+		if !txnRes.Succeeded {
+			continue
+		}
+		if previousID != "" && previousID != schemaID(schemaData.GetSchema()) {
+			return repo.removeIDIndex(ctx, previousID, key)
+		}
+		return nil
+	}
+	return ErrConcurrentModification
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+func TestModifyConfigSchema(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	key := "org/ns/name/v1.0.0"
+	mustSave(t, repo, key, WithTTL(time.Minute))
+	lease := fake.get(key).Lease
+
+	err := repo.ModifyConfigSchema(context.Background(), key, func(current *pb.ConfigSchemaData) error {
+		current.Labels = map[string]string{"env": "prod"}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ModifyConfigSchema: %v", err)
+	}
+	if labels := storedData(t, fake, key).GetLabels(); labels["env"] != "prod" {
+		t.Errorf("got labels %v", labels)
+	}
+	if fake.get(key).Lease != lease {
+		t.Errorf("the modification dropped the lease")
+	}
+
+	failure := errors.New("rejected")
+	before := fake.currentRevision()
+	err = repo.ModifyConfigSchema(context.Background(), key, func(*pb.ConfigSchemaData) error { return failure })
+	if !errors.Is(err, failure) || fake.currentRevision() != before {
+		t.Errorf("failing callback: got %v and a write: %t", err, fake.currentRevision() != before)
+	}
+	err = repo.ModifyConfigSchema(context.Background(), "org/ns/name/v2.0.0", func(*pb.ConfigSchemaData) error { return nil })
+	if !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing key: got %v, want ErrSchemaNotFound", err)
+	}
+}
+
+func TestModifyConfigSchemaRetriesOnConflict(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	key := "org/ns/name/v1.0.0"
+	mustSave(t, repo, key)
+
+	calls := 0
+	err := repo.ModifyConfigSchema(context.Background(), key, func(current *pb.ConfigSchemaData) error {
+		calls++
+		if calls == 1 {
+			// A concurrent writer gets in between the read and the write.
+			concurrent := storedData(t, fake, key)
+			concurrent.Labels = map[string]string{"team": "a"}
+			value, err := defaultMarshaler(concurrent)
+			if err != nil {
+				return err
+			}
+			fake.put(key, string(value))
+		}
+		if current.Labels == nil {
+			current.Labels = make(map[string]string)
+		}
+		current.Labels["env"] = "prod"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ModifyConfigSchema: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("the callback ran %d times, want 2", calls)
+	}
+	if labels := storedData(t, fake, key).GetLabels(); labels["team"] != "a" || labels["env"] != "prod" {
+		t.Errorf("got labels %v, want both writes", labels)
+	}
+}
+
+func TestModifyConfigSchemaGivesUp(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	key := "org/ns/name/v1.0.0"
+	mustSave(t, repo, key)
+	value := string(fake.get(key).Value)
+
+	calls := 0
+	err := repo.ModifyConfigSchema(context.Background(), key, func(*pb.ConfigSchemaData) error {
+		calls++
+		fake.put(key, value)
+		return nil
+	})
+	if !errors.Is(err, ErrConcurrentModification) {
+		t.Errorf("got %v, want ErrConcurrentModification", err)
+	}
+	if calls != maxModifyAttempts {
+		t.Errorf("the callback ran %d times, want %d", calls, maxModifyAttempts)
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// MoveSchema relocates the schema stored under srcKey to dstKey in a
+// single transaction. The stored value, including its creation time, is
+// carried over unchanged.
+func (repo *EtcdRepository) MoveSchema(ctx context.Context, srcKey, dstKey string) error {
+	ctx, span := repo.startSpan(ctx, "Repository.MoveSchema")
+	defer span.End()
+
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	dstDetails, err := repo.getSchemaDetailsFromKey(dstKey)
+	if err != nil {
+		return err
+	}
+	if dstDetails.GetVersion() == LatestVersion || isReservedVersion(dstDetails.GetVersion()) {
+		return ErrReservedVersion
+	}
+	if err := repo.validateSegments(dstDetails); err != nil {
+		return err
+	}
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	if len(res.Kvs) == 0 {
+		return &SchemaNotFoundError{Key: srcKey}
+	}
+	var schemaData pb.ConfigSchemaData
+	if err := repo.decodeSchemaData(res.Kvs[0].Value, &schemaData); err != nil {
+		return err
+	}
+	srcDetails, err := repo.getSchemaDetailsFromKey(srcKey)
+	if err != nil {
+		return err
+	}
+	if srcDetails.GetOrganization() != dstDetails.GetOrganization() {
+		if err := repo.checkOrgQuota(ctx, dstDetails.GetOrganization(), int64(len(res.Kvs[0].Value))); err != nil {
+			return err
+		}
+	}
+	ops := append([]clientv3.Op{
+		clientv3.OpPut(dstKey, string(res.Kvs[0].Value)),
+		clientv3.OpDelete(srcKey),
+	}, repo.idIndexOps(dstKey, schemaData.GetSchema())...)
+	txnRes, err := repo.kv.Txn(ctx).
+		If(
+			clientv3.Compare(clientv3.ModRevision(srcKey), "=", res.Kvs[0].ModRevision),
+			clientv3.Compare(clientv3.CreateRevision(dstKey), "=", 0),
+		).
+		Then(ops...).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if txnRes.Succeeded {
+		// The source organization's index entry is left behind by a move
+		// across organizations.
+		if id := schemaID(schemaData.GetSchema()); id != "" {
+			if err := repo.removeIDIndex(ctx, id, srcKey); err != nil {
+				return err
+			}
+		}
+		return repo.refreshLatestPointers(ctx, []string{srcKey, dstKey})
+	}
+	exists, err := repo.SchemaExists(ctx, dstKey)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return &SchemaExistsError{Key: dstKey}
+	}
+	return ErrConcurrentModification
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMoveSchema(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mustSave(t, repo, "old/ns/name/v1.0.0", WithCreationTime(created), WithLabels(map[string]string{"team": "a"}))
+	value := fake.get("old/ns/name/v1.0.0").Value
+
+	if err := repo.MoveSchema(ctx, "old/ns/name/v1.0.0", "new/ns/name/v1.0.0"); err != nil {
+		t.Fatalf("MoveSchema: %v", err)
+	}
+	if fake.get("old/ns/name/v1.0.0") != nil {
+		t.Errorf("the source survived the move")
+	}
+	moved := fake.get("new/ns/name/v1.0.0")
+	if moved == nil || !bytes.Equal(moved.Value, value) {
+		t.Fatalf("the destination does not hold the source value: %v", moved)
+	}
+	latest, err := repo.GetLatestConfigSchema(ctx, "new", "ns", "name")
+	if err != nil || !latest.GetSchemaData().GetCreationTime().AsTime().Equal(created) {
+		t.Errorf("got latest %v, %v", latest, err)
+	}
+	if _, err := repo.GetLatestConfigSchema(ctx, "old", "ns", "name"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("the source still has a latest version: %v", err)
+	}
+}
+
+func TestMoveSchemaAbortsOnConflict(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	mustSave(t, repo, "old/ns/name/v1.0.0")
+	mustSave(t, repo, "new/ns/name/v1.0.0", WithLabels(map[string]string{"kept": "yes"}))
+	before := fake.currentRevision()
+
+	var exists *SchemaExistsError
+	if err := repo.MoveSchema(ctx, "old/ns/name/v1.0.0", "new/ns/name/v1.0.0"); !errors.As(err, &exists) || exists.Key != "new/ns/name/v1.0.0" {
+		t.Errorf("got %v, want SchemaExistsError for the destination", err)
+	}
+	if fake.currentRevision() != before {
+		t.Errorf("the aborted move wrote to etcd")
+	}
+	if err := repo.MoveSchema(ctx, "old/ns/name/v9.0.0", "new/ns/name/v9.0.0"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing source: got %v, want ErrSchemaNotFound", err)
+	}
+	if err := repo.MoveSchema(ctx, "old/ns/name/v1.0.0", "new/ns/name/"+ActiveVersion); !errors.Is(err, ErrReservedVersion) {
+		t.Errorf("reserved destination: got %v, want ErrReservedVersion", err)
+	}
+}
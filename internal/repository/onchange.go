@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// CallbackOverflowPolicy decides what OnChange does with an event when the
+// configured number of callbacks is already running.
+type CallbackOverflowPolicy int
+
+const (
+	// CallbackQueue holds the event, and with it the watch, until a
+	// running callback finishes.
+	CallbackQueue CallbackOverflowPolicy = iota
+	// CallbackDrop discards the event and counts it in the
+	// repository.callbacks.dropped metric.
+	CallbackDrop
+)
+
+// OnChange runs callback in its own goroutine for every event of
+// WatchSchemas(ctx, prefix) until ctx is canceled. The number of callbacks
+// running at once is bounded by WithCallbackConcurrency.
+func (repo *EtcdRepository) OnChange(ctx context.Context, prefix string, callback func(SchemaEvent)) {
+	events := repo.WatchSchemas(ctx, prefix)
+	go repo.dispatchChanges(ctx, prefix, events, callback)
+}
+
+func (repo *EtcdRepository) dispatchChanges(ctx context.Context, prefix string, events <-chan SchemaEvent, callback func(SchemaEvent)) {
+	if repo.callbackLimit <= 0 {
+		for event := range events {
+			go callback(event)
+		}
+		return
+	}
+
+	dropped, err := repo.meter().Int64Counter("repository.callbacks.dropped",
+		metric.WithDescription("Schema change events discarded because the callback concurrency limit was reached"),
+	)
+	if err != nil {
+		repo.logger.WarnContext(ctx, "cannot create dropped callbacks counter", "error", err)
+	}
+	slots := make(chan struct{}, repo.callbackLimit)
+	for event := range events {
+		if repo.callbackPolicy == CallbackDrop {
+			select {
+			case slots <- struct{}{}:
+			default:
+				if dropped != nil {
+					dropped.Add(ctx, 1, metric.WithAttributes(attribute.String("prefix", prefix)))
+				}
+				repo.logger.WarnContext(ctx, "dropping schema change callback", "prefix", prefix, "key", event.Key)
+				continue
+			}
+		} else {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		go func(event SchemaEvent) {
+			defer func() { <-slots }()
+			callback(event)
+		}(event)
+	}
+}
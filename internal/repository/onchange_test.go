@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+)
+
+// countingMeterProvider hands out Int64Counters that add into one shared
+// total per instrument name; every other instrument is a no-op.
+type countingMeterProvider struct {
+	metricnoop.MeterProvider
+	mu     sync.Mutex
+	totals map[string]*atomic.Int64
+}
+
+func newCountingMeterProvider() *countingMeterProvider {
+	return &countingMeterProvider{totals: make(map[string]*atomic.Int64)}
+}
+
+func (provider *countingMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return countingMeter{provider: provider}
+}
+
+func (provider *countingMeterProvider) total(name string) int64 {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if total, ok := provider.totals[name]; ok {
+		return total.Load()
+	}
+	return 0
+}
+
+type countingMeter struct {
+	metricnoop.Meter
+	provider *countingMeterProvider
+}
+
+func (meter countingMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	meter.provider.mu.Lock()
+	defer meter.provider.mu.Unlock()
+	total, ok := meter.provider.totals[name]
+	if !ok {
+		total = new(atomic.Int64)
+		meter.provider.totals[name] = total
+	}
+	return countingCounter{total: total}, nil
+}
+
+type countingCounter struct {
+	metricnoop.Int64Counter
+	total *atomic.Int64
+}
+
+func (counter countingCounter) Add(_ context.Context, incr int64, _ ...metric.AddOption) {
+	counter.total.Add(incr)
+}
+
+func waitForWatch(t *testing.T, fake *fakeEtcd) {
+	t.Helper()
+	for fake.callCount("Watch") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOnChangeQueuesWithinLimit(t *testing.T) {
+	repo, fake := newTestRepo(t, WithCallbackConcurrency(2, CallbackQueue))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var running, peak atomic.Int64
+	release := make(chan struct{})
+	done := make(chan string, 10)
+	repo.OnChange(ctx, "org/ns/name/v", func(event SchemaEvent) {
+		now := running.Add(1)
+		for {
+			old := peak.Load()
+			if now <= old || peak.CompareAndSwap(old, now) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		done <- event.Key
+	})
+	waitForWatch(t, fake)
+
+	for i := 0; i < 5; i++ {
+		mustSave(t, repo, fmt.Sprintf("org/ns/name/v1.%d.0", i))
+	}
+	for running.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	for i := 0; i < 5; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of 5 queued callbacks ran", i)
+		}
+	}
+	if got := peak.Load(); got != 2 {
+		t.Errorf("at most %d callbacks ran at once, want 2", got)
+	}
+}
+
+func TestOnChangeDropsOverLimit(t *testing.T) {
+	meters := newCountingMeterProvider()
+	repo, fake := newTestRepo(t, WithCallbackConcurrency(1, CallbackDrop), WithMeterProvider(meters))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan string, 10)
+	release := make(chan struct{})
+	repo.OnChange(ctx, "org/ns/name/v", func(event SchemaEvent) {
+		started <- event.Key
+		<-release
+	})
+	waitForWatch(t, fake)
+
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	if key := <-started; key != "org/ns/name/v1.0.0" {
+		t.Fatalf("first callback got %q", key)
+	}
+	mustSave(t, repo, "org/ns/name/v1.1.0")
+	mustSave(t, repo, "org/ns/name/v1.2.0")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for meters.total("repository.callbacks.dropped") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("dropped %d events, want 2", meters.total("repository.callbacks.dropped"))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	// The slot is free again, so the next event runs.
+	mustSave(t, repo, "org/ns/name/v1.3.0")
+	select {
+	case key := <-started:
+		if key != "org/ns/name/v1.3.0" {
+			t.Errorf("callback after release got %q, want v1.3.0", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no callback ran after the slot was released")
+	}
+}
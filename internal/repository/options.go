@@ -0,0 +1,414 @@
+package repository
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+)
+
+type Option func(*EtcdRepository)
+
+// WithBasePrefix keeps all keys of the repository under base, so several
+// applications can share one etcd cluster. Keys passed to and returned by
+// the repository stay relative to base.
+func WithBasePrefix(base string) Option {
+	return func(repo *EtcdRepository) {
+		repo.basePrefix = base
+	}
+}
+
+func WithMaxResults(max int64) Option {
+	return func(repo *EtcdRepository) {
+		repo.maxResults = max
+	}
+}
+
+// WithMaxPageSize sets the largest page GetSchemasByPrefixPage returns;
+// larger requests are clamped to it.
+func WithMaxPageSize(max int64) Option {
+	return func(repo *EtcdRepository) {
+		repo.maxPageSize = max
+	}
+}
+
+// WithIteratorPageSize sets how many schemas IterateSchemasByPrefix and
+// StreamAll fetch from etcd per request.
+func WithIteratorPageSize(size int64) Option {
+	return func(repo *EtcdRepository) {
+		repo.iteratorPageSize = size
+	}
+}
+
+// WithMaxStaleness lets reads be served by any member, without a round
+// trip to the leader, as long as the data is provably at most bound behind
+// it; otherwise they fall back to linearizable reads. See boundedStaleGet
+// for how the lag is measured.
+func WithMaxStaleness(bound time.Duration) Option {
+	return func(repo *EtcdRepository) {
+		repo.maxStaleness = bound
+		repo.freshness = nil
+		if bound > 0 {
+			repo.freshness = &freshnessMark{}
+		}
+	}
+}
+
+// WithStreamConcurrency lets StreamAll and exports decode up to workers
+// entries at once. Entries are then no longer emitted in key order.
+func WithStreamConcurrency(workers int) Option {
+	return func(repo *EtcdRepository) {
+		repo.streamWorkers = workers
+	}
+}
+
+func WithMarshaler(marshal Marshaler) Option {
+	return func(repo *EtcdRepository) {
+		repo.marshal = marshal
+	}
+}
+
+func WithUnmarshaler(unmarshal Unmarshaler) Option {
+	return func(repo *EtcdRepository) {
+		repo.unmarshal = unmarshal
+	}
+}
+
+// WithLogger sets the logger for repository events; nil discards them.
+func WithLogger(logger *slog.Logger) Option {
+	return func(repo *EtcdRepository) {
+		if logger == nil {
+			logger = slog.New(slog.DiscardHandler)
+		}
+		repo.logger = logger
+	}
+}
+
+// WithTracerProvider sets the provider of the repository's spans instead
+// of the global one; nil disables them like WithTracingDisabled.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(repo *EtcdRepository) {
+		if provider == nil {
+			provider = noop.NewTracerProvider()
+		}
+		repo.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the provider of the repository's metrics instead
+// of the global one; nil discards them.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(repo *EtcdRepository) {
+		if provider == nil {
+			provider = metricnoop.NewMeterProvider()
+		}
+		repo.meterProvider = provider
+	}
+}
+
+func WithTracingDisabled() Option {
+	return func(repo *EtcdRepository) {
+		repo.tracingDisabled = true
+	}
+}
+
+func WithKeyCodec(codec KeyCodec) Option {
+	return func(repo *EtcdRepository) {
+		repo.codec = codec
+	}
+}
+
+func WithYAMLConverter(converter YAMLConverter) Option {
+	return func(repo *EtcdRepository) {
+		repo.converter = converter
+	}
+}
+
+func WithVersionComparator(comparator VersionComparator) Option {
+	return func(repo *EtcdRepository) {
+		repo.comparator = comparator
+	}
+}
+
+// WithAutoSyncInterval makes the client refresh its endpoints from the
+// cluster member list every interval. Zero, the default, disables it.
+func WithAutoSyncInterval(interval time.Duration) Option {
+	return func(repo *EtcdRepository) {
+		repo.config.AutoSyncInterval = interval
+	}
+}
+
+// WithCallbackConcurrency caps the OnChange callbacks running at once to
+// limit; events arriving beyond it are handled according to policy.
+func WithCallbackConcurrency(limit int, policy CallbackOverflowPolicy) Option {
+	return func(repo *EtcdRepository) {
+		repo.callbackLimit = limit
+		repo.callbackPolicy = policy
+	}
+}
+
+// WithSegmentPattern replaces DefaultSegmentPattern as the rule saved
+// organization, namespace and schema names must match.
+func WithSegmentPattern(pattern *regexp.Regexp) Option {
+	return func(repo *EtcdRepository) {
+		repo.segmentPattern = pattern
+	}
+}
+
+// WithDialOptions appends gRPC dial options, such as interceptors or a
+// custom dialer, to those the etcd client connects with.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(repo *EtcdRepository) {
+		repo.config.DialOptions = append(repo.config.DialOptions, opts...)
+	}
+}
+
+// WithKeepAlive makes the client ping the server after interval without
+// activity and close the connection if no reply arrives within timeout.
+func WithKeepAlive(interval, timeout time.Duration) Option {
+	return func(repo *EtcdRepository) {
+		repo.config.DialKeepAliveTime = interval
+		repo.config.DialKeepAliveTimeout = timeout
+	}
+}
+
+func WithStorageFormat(format StorageFormat) Option {
+	return func(repo *EtcdRepository) {
+		repo.storageFormat = format
+	}
+}
+
+// WithReadOnly makes every mutating method fail with ErrReadOnly without
+// contacting etcd; reads behave normally.
+func WithReadOnly() Option {
+	return func(repo *EtcdRepository) {
+		repo.readOnly = true
+	}
+}
+
+// WithWriteCoalescing makes concurrent saves of identical content under
+// the same key share a single etcd round trip and its result. The shared
+// save is not canceled with any one caller; each caller stops waiting for
+// it when its own context ends.
+func WithWriteCoalescing() Option {
+	return func(repo *EtcdRepository) {
+		repo.writes = &singleflight.Group{}
+	}
+}
+
+// WithOrgQuota caps the total stored bytes of each organization. Saves,
+// promotions, moves into the organization and modifications that grow a
+// schema fail with QuotaExceededError when they would exceed it. Zero
+// means unlimited.
+func WithOrgQuota(maxBytes int64) Option {
+	return func(repo *EtcdRepository) {
+		repo.orgQuota = maxBytes
+	}
+}
+
+// WithMinDeleteSegments sets how many complete key segments a prefix
+// needs before DeleteSchemasByPrefix accepts it without WithForce. The
+// default of 2 refuses organization-wide deletes; zero disables the check.
+func WithMinDeleteSegments(n int) Option {
+	return func(repo *EtcdRepository) {
+		repo.minDeleteSegments = n
+	}
+}
+
+// WithSchemaCacheSize bounds how many compiled schemas ValidateConfigs
+// keeps for reuse; zero disables the cache.
+func WithSchemaCacheSize(size int) Option {
+	return func(repo *EtcdRepository) {
+		repo.schemas = nil
+		if size > 0 {
+			repo.schemas = newSchemaCache(size)
+		}
+	}
+}
+
+type SaveOption func(*saveOptions)
+
+type saveOptions struct {
+	monotonicVersions  bool
+	labels             map[string]string
+	creationTime       time.Time
+	idempotencyToken   string
+	ttl                time.Duration
+	minConsumerVersion string
+	author             string
+	// activate repoints the active version at the saved one in the same
+	// transaction; set by PublishConfigSchema.
+	activate bool
+}
+
+func newSaveOptions(opts []SaveOption) *saveOptions {
+	options := &saveOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// coalescingKey identifies saves that are interchangeable for write
+// coalescing: same key, same schema and same options.
+func (options *saveOptions) coalescingKey(key, schema string) string {
+	return fmt.Sprintf("%s\x00%s\x00%v\x00%t\x00%d\x00%s\x00%d\x00%s\x00%s\x00%t", key, schema, options.labels, options.monotonicVersions,
+		options.creationTime.UnixNano(), options.idempotencyToken, options.ttl, options.minConsumerVersion, options.author, options.activate)
+}
+
+func WithMonotonicVersions() SaveOption {
+	return func(options *saveOptions) {
+		options.monotonicVersions = true
+	}
+}
+
+func WithLabels(labels map[string]string) SaveOption {
+	return func(options *saveOptions) {
+		options.labels = labels
+	}
+}
+
+// WithCreationTime stores t as the creation time instead of the time of
+// the save, so imported schemas keep their original timestamps.
+func WithCreationTime(t time.Time) SaveOption {
+	return func(options *saveOptions) {
+		options.creationTime = t
+	}
+}
+
+// WithIdempotencyToken stores token with the schema, hidden from readers.
+// A retried save that carries the same token and schema as the stored one
+// succeeds instead of failing because the key already exists.
+func WithIdempotencyToken(token string) SaveOption {
+	return func(options *saveOptions) {
+		options.idempotencyToken = token
+	}
+}
+
+// WithMinConsumerVersion stores the lowest consumer version, a SemVer
+// string, that is compatible with the schema. See CheckCompatibility.
+func WithMinConsumerVersion(version string) SaveOption {
+	return func(options *saveOptions) {
+		options.minConsumerVersion = version
+	}
+}
+
+// WithAuthor records who saved the schema, e.g. the authenticated user.
+func WithAuthor(author string) SaveOption {
+	return func(options *saveOptions) {
+		options.author = author
+	}
+}
+
+// WithTTL attaches the schema to a lease of the given duration, after which
+// etcd removes it. etcd leases have a granularity of one second.
+func WithTTL(ttl time.Duration) SaveOption {
+	return func(options *saveOptions) {
+		options.ttl = ttl
+	}
+}
+
+type ListOption func(*listOptions)
+
+type listOptions struct {
+	withoutBody bool
+	sortBy      SortField
+	descending  bool
+	skipInvalid bool
+	skipped     *[]string
+	emptySlice  bool
+}
+
+type SortField int
+
+const (
+	SortByVersion SortField = iota
+	SortByCreationTime
+)
+
+func (options *listOptions) noMatches() []*pb.ConfigSchema {
+	if options.emptySlice {
+		return []*pb.ConfigSchema{}
+	}
+	return nil
+}
+
+func newListOptions(opts []ListOption) *listOptions {
+	options := &listOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// WithoutBody leaves the schema body empty and skips its conversion to
+// YAML; details and the remaining metadata are still returned.
+func WithoutBody() ListOption {
+	return func(options *listOptions) {
+		options.withoutBody = true
+	}
+}
+
+// WithSortBy orders the listing by field instead of by version ascending.
+func WithSortBy(field SortField, descending bool) ListOption {
+	return func(options *listOptions) {
+		options.sortBy = field
+		options.descending = descending
+	}
+}
+
+// Descending reverses the order of the listing, e.g. newest version first.
+// The result is the exact reverse of the ascending listing.
+func Descending() ListOption {
+	return func(options *listOptions) {
+		options.descending = true
+	}
+}
+
+// WithEmptySlice makes listings return an empty, non-nil slice instead of
+// nil when no schema matches.
+func WithEmptySlice() ListOption {
+	return func(options *listOptions) {
+		options.emptySlice = true
+	}
+}
+
+// SkipInvalid makes listings skip entries that cannot be decoded as
+// schemas instead of failing. The keys of skipped entries are appended to
+// skipped when it is not nil.
+func SkipInvalid(skipped *[]string) ListOption {
+	return func(options *listOptions) {
+		options.skipInvalid = true
+		options.skipped = skipped
+	}
+}
+
+type DeleteOption func(*deleteOptions)
+
+type deleteOptions struct {
+	force bool
+}
+
+func newDeleteOptions(opts []DeleteOption) *deleteOptions {
+	options := &deleteOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// WithForce lets DeleteSchemasByPrefix delete under prefixes shorter than
+// the configured minimum.
+func WithForce() DeleteOption {
+	return func(options *deleteOptions) {
+		options.force = true
+	}
+}
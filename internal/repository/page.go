@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const defaultMaxPageSize = 500
+
+// GetSchemasByPrefixPage returns up to pageSize schemas under prefix in key
+// order, starting where the page identified by pageToken left off; an
+// empty token starts at the beginning. The returned token is empty once
+// the last page has been read.
+//
+// pageSize is clamped to the configured maximum (see WithMaxPageSize), and
+// zero or negative values select the default page size.
+func (repo *EtcdRepository) GetSchemasByPrefixPage(ctx context.Context, prefix string, pageSize int64, pageToken string) ([]*pb.ConfigSchema, string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetSchemasByPrefixPage")
+	defer span.End()
+
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if repo.maxPageSize > 0 && pageSize > repo.maxPageSize {
+		pageSize = repo.maxPageSize
+	}
+	start := prefix
+	if pageToken != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(pageToken)
+		if err != nil || !strings.HasPrefix(string(decoded), prefix) {
+			return nil, "", fmt.Errorf("%w: '%s'", ErrInvalidPageToken, pageToken)
+		}
+		start = string(decoded)
+	}
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, start, clientv3.WithRange(clientv3.GetPrefixRangeEnd(prefix)), clientv3.WithLimit(pageSize))
+	if err != nil {
+		return nil, "", err
+	}
+	schemas := make([]*pb.ConfigSchema, 0, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		if repo.isReservedKey(string(kv.Key)) {
+			continue
+		}
+		schema, err := repo.decodeConfigSchema(kv)
+		if err != nil {
+			return nil, "", err
+		}
+		schemas = append(schemas, schema)
+	}
+	nextToken := ""
+	if res.More && len(res.Kvs) > 0 {
+		nextToken = base64.RawURLEncoding.EncodeToString([]byte(string(res.Kvs[len(res.Kvs)-1].Key) + "\x00"))
+	}
+	return schemas, nextToken, nil
+}
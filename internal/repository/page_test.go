@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestGetSchemasByPrefixPageClampsPageSize(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		name     string
+		opts     []Option
+		pageSize int64
+		want     int64
+	}{
+		{"zero", nil, 0, defaultPageSize},
+		{"negative", nil, -5, defaultPageSize},
+		{"default maximum", nil, 10000, defaultMaxPageSize},
+		{"configured maximum", []Option{WithMaxPageSize(3)}, 50, 3},
+		{"within maximum", []Option{WithMaxPageSize(3)}, 2, 2},
+	} {
+		repo, fake := newTestRepo(t, test.opts...)
+		if _, _, err := repo.GetSchemasByPrefixPage(ctx, "org/", test.pageSize, ""); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if limit := fake.lastRange().GetLimit(); limit != test.want {
+			t.Errorf("%s: requested %d, want %d", test.name, limit, test.want)
+		}
+	}
+}
+
+func TestGetSchemasByPrefixPageWalksAllPages(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0", "v1.4.0")
+
+	var versions []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatalf("the pages never end")
+		}
+		schemas, next, err := repo.GetSchemasByPrefixPage(ctx, "org/ns/name/", 2, token)
+		if err != nil {
+			t.Fatalf("GetSchemasByPrefixPage: %v", err)
+		}
+		versions = append(versions, schemaVersions(schemas)...)
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	if want := []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0", "v1.4.0"}; !slices.Equal(versions, want) {
+		t.Errorf("got %v, want %v", versions, want)
+	}
+
+	if _, _, err := repo.GetSchemasByPrefixPage(ctx, "other/", 2, token); !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("token of another prefix: got %v, want ErrInvalidPageToken", err)
+	}
+}
@@ -0,0 +1,314 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jtomic1/config-schema-service/internal/validators"
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+func (repo *EtcdRepository) PatchConfigSchema(ctx context.Context, key string, mergePatch []byte) error {
+	ctx, span := repo.startSpan(ctx, "Repository.PatchConfigSchema")
+	defer span.End()
+
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	var patch interface{}
+	if err := decodeJSON(mergePatch, &patch); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return repo.updateSchemaBody(ctx, key, func(document interface{}) (interface{}, error) {
+		return applyMergePatch(document, patch), nil
+	})
+}
+
+// applyMergePatch implements RFC 7386: objects are merged recursively,
+// null removes a member and any other value replaces the target.
+func applyMergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = applyMergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (repo *EtcdRepository) ApplyJSONPatch(ctx context.Context, key string, patch []byte) error {
+	ctx, span := repo.startSpan(ctx, "Repository.ApplyJSONPatch")
+	defer span.End()
+
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return repo.updateSchemaBody(ctx, key, func(document interface{}) (interface{}, error) {
+		for i, operation := range operations {
+			var err error
+			document, err = applyJSONPatchOperation(document, operation)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+			}
+		}
+		return document, nil
+	})
+}
+
+func applyJSONPatchOperation(document interface{}, operation jsonPatchOperation) (interface{}, error) {
+	path, err := parseJSONPointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		var value interface{}
+		if err := decodeJSON(operation.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		switch operation.Op {
+		case "add":
+			return jsonPointerAdd(document, path, value)
+		case "replace":
+			if _, err := jsonPointerGet(document, path); err != nil {
+				return nil, err
+			}
+			document, err = jsonPointerRemove(document, path)
+			if err != nil {
+				return nil, err
+			}
+			return jsonPointerAdd(document, path, value)
+		default:
+			current, err := jsonPointerGet(document, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, ErrPatchTestFailed
+			}
+			return document, nil
+		}
+	case "remove":
+		return jsonPointerRemove(document, path)
+	case "move", "copy":
+		from, err := parseJSONPointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := jsonPointerGet(document, from)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "copy" {
+			return jsonPointerAdd(document, path, deepCopyJSON(value))
+		}
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return nil, fmt.Errorf("%w: cannot move a value into one of its children", ErrInvalidPatch)
+		}
+		document, err = jsonPointerRemove(document, from)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(document, path, value)
+	}
+	return nil, fmt.Errorf("%w: unknown operation '%s'", ErrInvalidPatch, operation.Op)
+}
+
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidPath, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func jsonPointerGet(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch current := node.(type) {
+		case map[string]interface{}:
+			child, ok := current[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member '%s' not found", ErrInvalidPath, token)
+			}
+			node = child
+		case []interface{}:
+			index, err := arrayIndex(token, len(current)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = current[index]
+		default:
+			return nil, fmt.Errorf("%w: cannot traverse into a scalar at '%s'", ErrInvalidPath, token)
+		}
+	}
+	return node, nil
+}
+
+func jsonPointerAdd(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token := path[0]
+	switch current := node.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			current[token] = value
+			return current, nil
+		}
+		child, ok := current[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: member '%s' not found", ErrInvalidPath, token)
+		}
+		updated, err := jsonPointerAdd(child, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		current[token] = updated
+		return current, nil
+	case []interface{}:
+		if len(path) == 1 {
+			index := len(current)
+			if token != "-" {
+				var err error
+				if index, err = arrayIndex(token, len(current)); err != nil {
+					return nil, err
+				}
+			}
+			current = append(current, nil)
+			copy(current[index+1:], current[index:])
+			current[index] = value
+			return current, nil
+		}
+		index, err := arrayIndex(token, len(current)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := jsonPointerAdd(current[index], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		current[index] = updated
+		return current, nil
+	}
+	return nil, fmt.Errorf("%w: cannot add into a scalar at '%s'", ErrInvalidPath, token)
+}
+
+func jsonPointerRemove(node interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPath)
+	}
+	token := path[0]
+	switch current := node.(type) {
+	case map[string]interface{}:
+		child, ok := current[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: member '%s' not found", ErrInvalidPath, token)
+		}
+		if len(path) == 1 {
+			delete(current, token)
+			return current, nil
+		}
+		updated, err := jsonPointerRemove(child, path[1:])
+		if err != nil {
+			return nil, err
+		}
+		current[token] = updated
+		return current, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(current)-1)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 1 {
+			return append(current[:index], current[index+1:]...), nil
+		}
+		updated, err := jsonPointerRemove(current[index], path[1:])
+		if err != nil {
+			return nil, err
+		}
+		current[index] = updated
+		return current, nil
+	}
+	return nil, fmt.Errorf("%w: cannot remove from a scalar at '%s'", ErrInvalidPath, token)
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: array index '%s' out of range", ErrInvalidPath, token)
+	}
+	return index, nil
+}
+
+func deepCopyJSON(value interface{}) interface{} {
+	switch current := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(current))
+		for name, child := range current {
+			copied[name] = deepCopyJSON(child)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(current))
+		for i, child := range current {
+			copied[i] = deepCopyJSON(child)
+		}
+		return copied
+	}
+	return value
+}
+
+func (repo *EtcdRepository) updateSchemaBody(ctx context.Context, key string, update func(document interface{}) (interface{}, error)) error {
+	return repo.ModifyConfigSchema(ctx, key, func(schemaData *pb.ConfigSchemaData) error {
+		var document interface{}
+		if err := decodeJSON([]byte(schemaData.GetSchema()), &document); err != nil {
+			return err
+		}
+		document, err := update(document)
+		if err != nil {
+			return err
+		}
+		schemaJson, err := json.Marshal(document)
+		if err != nil {
+			return err
+		}
+		if _, err := validators.IsSchemaValid(string(schemaJson)); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+		}
+		schemaData.Schema = string(schemaJson)
+		return nil
+	})
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+const patchKey = "org/ns/name/v1.0.0"
+
+func TestPatchConfigSchema(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"add", `{"required":["port"]}`, `{"properties":{"port":{"type":"integer"}},"required":["port"],"type":"object"}`},
+		{"change", `{"properties":{"port":{"type":"string"}}}`, `{"properties":{"port":{"type":"string"}},"type":"object"}`},
+		{"delete", `{"properties":null}`, `{"type":"object"}`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repo, fake := newTestRepo(t)
+			mustSave(t, repo, patchKey)
+			created := storedData(t, fake, patchKey).GetCreationTime().AsTime()
+
+			if err := repo.PatchConfigSchema(context.Background(), patchKey, []byte(test.patch)); err != nil {
+				t.Fatalf("PatchConfigSchema: %v", err)
+			}
+			patched := storedData(t, fake, patchKey)
+			if patched.GetSchema() != test.want {
+				t.Errorf("got %s, want %s", patched.GetSchema(), test.want)
+			}
+			if !patched.GetCreationTime().AsTime().Equal(created) {
+				t.Errorf("creation time changed from %v to %v", created, patched.GetCreationTime().AsTime())
+			}
+		})
+	}
+}
+
+func TestPatchConfigSchemaRejectsInvalidResults(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	mustSave(t, repo, patchKey)
+	before := fake.get(patchKey).ModRevision
+
+	for _, test := range []struct {
+		patch string
+		want  error
+	}{
+		{`{"type":`, ErrInvalidPatch},
+		{`{"type":"no-such-type"}`, ErrInvalidSchema},
+	} {
+		if err := repo.PatchConfigSchema(context.Background(), patchKey, []byte(test.patch)); !errors.Is(err, test.want) {
+			t.Errorf("%s: got %v, want %v", test.patch, err, test.want)
+		}
+	}
+	if after := fake.get(patchKey).ModRevision; after != before {
+		t.Errorf("rejected patches rewrote the schema")
+	}
+}
+
+func TestApplyJSONPatchReplace(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	mustSave(t, repo, patchKey)
+	created := storedData(t, fake, patchKey).GetCreationTime().AsTime()
+
+	patch := `[{"op":"test","path":"/properties/port/type","value":"integer"},{"op":"replace","path":"/properties/port/type","value":"string"}]`
+	if err := repo.ApplyJSONPatch(context.Background(), patchKey, []byte(patch)); err != nil {
+		t.Fatalf("ApplyJSONPatch: %v", err)
+	}
+	patched := storedData(t, fake, patchKey)
+	if want := `{"properties":{"port":{"type":"string"}},"type":"object"}`; patched.GetSchema() != want {
+		t.Errorf("got %s, want %s", patched.GetSchema(), want)
+	}
+	if !patched.GetCreationTime().AsTime().Equal(created) {
+		t.Errorf("creation time changed from %v to %v", created, patched.GetCreationTime().AsTime())
+	}
+}
+
+func TestApplyJSONPatchFailsAtomically(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	mustSave(t, repo, patchKey)
+	before := fake.get(patchKey).ModRevision
+
+	for _, test := range []struct {
+		name  string
+		patch string
+		want  error
+	}{
+		{"failing test", `[{"op":"replace","path":"/type","value":"array"},{"op":"test","path":"/properties/port/type","value":"string"}]`, ErrPatchTestFailed},
+		{"bad path", `[{"op":"replace","path":"/type","value":"array"},{"op":"remove","path":"/properties/host"}]`, ErrInvalidPath},
+		{"malformed pointer", `[{"op":"remove","path":"properties"}]`, ErrInvalidPath},
+		{"not a list", `{"op":"remove","path":"/type"}`, ErrInvalidPatch},
+	} {
+		if err := repo.ApplyJSONPatch(context.Background(), patchKey, []byte(test.patch)); !errors.Is(err, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+	if after := fake.get(patchKey).ModRevision; after != before {
+		t.Errorf("failed patches rewrote the schema")
+	}
+}
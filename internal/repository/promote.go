@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// PromoteNamespace copies the latest version of every schema in the
+// namespace to newVersion, one transaction per schema name. Names that
+// already have newVersion are skipped. It returns how many were promoted.
+func (repo *EtcdRepository) PromoteNamespace(ctx context.Context, org, namespace, newVersion string) (int, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.PromoteNamespace")
+	defer span.End()
+
+	if repo.readOnly {
+		return 0, ErrReadOnly
+	}
+	if newVersion == LatestVersion || isReservedVersion(newVersion) {
+		return 0, ErrReservedVersion
+	}
+	details, _, err := repo.listSchemaDetails(ctx, repo.getNamespacePrefix(org, namespace))
+	if err != nil {
+		return 0, err
+	}
+	latest := make(map[string]*pb.ConfigSchemaDetails)
+	var names []string
+	for _, schemaDetails := range details {
+		if _, ok := latest[schemaDetails.GetSchemaName()]; !ok {
+			names = append(names, schemaDetails.GetSchemaName())
+		}
+		latest[schemaDetails.GetSchemaName()] = schemaDetails
+	}
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	promoted := 0
+	for _, name := range names {
+		res, err := repo.kv.Get(ctx, repo.codec.EncodeKey(latest[name]))
+		if err != nil {
+			return promoted, err
+		}
+		if len(res.Kvs) == 0 {
+			continue
+		}
+		var schemaData pb.ConfigSchemaData
+		if err := repo.decodeSchemaData(res.Kvs[0].Value, &schemaData); err != nil {
+			return promoted, err
+		}
+		schemaData.CreationTime = timestamppb.New(time.Now())
+		schemaData.IdempotencyToken = ""
+		serializedData, err := repo.encodeSchemaData(&schemaData)
+		if err != nil {
+			return promoted, err
+		}
+		if err := repo.checkOrgQuota(ctx, org, int64(len(serializedData))); err != nil {
+			return promoted, err
+		}
+		key := repo.getSchemaKey(org, namespace, name, newVersion)
+		txnRes, err := repo.kv.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+			Then(append([]clientv3.Op{clientv3.OpPut(key, string(serializedData))}, repo.idIndexOps(key, schemaData.GetSchema())...)...).
+			Commit()
+		if err != nil {
+			return promoted, err
+		}
+		if !txnRes.Succeeded {
+			continue
+		}
+		promoted++
+		if err := repo.refreshLatestPointer(ctx, org, namespace, name); err != nil {
+			return promoted, err
+		}
+	}
+	return promoted, nil
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPromoteNamespace(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	mustSave(t, repo, "org/ns/payments/v1.9.0")
+	if err := repo.SaveConfigSchema(ctx, "org/ns/payments/v1.10.0", "type: string\n"); err != nil {
+		t.Fatalf("SaveConfigSchema: %v", err)
+	}
+	mustSave(t, repo, "org/ns/orders/v2.0.0")
+	mustSave(t, repo, "other/ns/orders/v2.0.0")
+
+	promoted, err := repo.PromoteNamespace(ctx, "org", "ns", "v3.0.0")
+	if err != nil || promoted != 2 {
+		t.Fatalf("got %d, %v, want 2 promoted", promoted, err)
+	}
+	for key, latest := range map[string]string{
+		"org/ns/payments/v3.0.0": "org/ns/payments/v1.10.0",
+		"org/ns/orders/v3.0.0":   "org/ns/orders/v2.0.0",
+	} {
+		if got, want := storedData(t, fake, key).GetSchema(), storedData(t, fake, latest).GetSchema(); got != want {
+			t.Errorf("%s: got body %s, want the body of %s", key, got, latest)
+		}
+		if version, err := repo.GetLatestVersionByPrefix(ctx, key[:len(key)-len("v3.0.0")]); err != nil || version != "v3.0.0" {
+			t.Errorf("%s: latest is %q, %v", key, version, err)
+		}
+	}
+	if fake.get("other/ns/orders/v3.0.0") != nil {
+		t.Errorf("promoted a schema of another organization")
+	}
+
+	// Names that already have the version are skipped.
+	mustSave(t, repo, "org/ns/users/v1.0.0")
+	promoted, err = repo.PromoteNamespace(ctx, "org", "ns", "v3.0.0")
+	if err != nil || promoted != 1 {
+		t.Errorf("second promotion: got %d, %v, want only users promoted", promoted, err)
+	}
+
+	if _, err := repo.PromoteNamespace(ctx, "org", "ns", LatestVersion); !errors.Is(err, ErrReservedVersion) {
+		t.Errorf("promoting to %s: got %v, want ErrReservedVersion", LatestVersion, err)
+	}
+}
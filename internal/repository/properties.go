@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PropertyInfo describes a top-level property of a schema. Type is empty
+// when the property declares none; several allowed types are joined with
+// "|".
+type PropertyInfo struct {
+	Name     string
+	Type     string
+	Required bool
+}
+
+// GetSchemaProperties returns the top-level properties of the schema under
+// key, sorted by name. Schemas without a "properties" block have none.
+func (repo *EtcdRepository) GetSchemaProperties(ctx context.Context, key string) ([]PropertyInfo, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetSchemaProperties")
+	defer span.End()
+
+	schemaJson, err := repo.getSchemaJSON(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	if err := decodeJSON(schemaJson, &document); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	properties, _ := document["properties"].(map[string]interface{})
+	required := stringSet(document["required"])
+	infos := make([]PropertyInfo, 0, len(properties))
+	for _, name := range sortedKeys(properties) {
+		property, _ := properties[name].(map[string]interface{})
+		infos = append(infos, PropertyInfo{
+			Name:     name,
+			Type:     strings.Join(schemaTypes(property), "|"),
+			Required: required[name],
+		})
+	}
+	return infos, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestGetSchemaProperties(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	schema := `
+type: object
+required: [port]
+properties:
+  port: {type: integer}
+  host: {type: string}
+  timeout: {type: [number, "null"]}
+  extra: {}
+`
+	if err := repo.SaveConfigSchema(ctx, "org/ns/name/v1.0.0", schema); err != nil {
+		t.Fatalf("SaveConfigSchema: %v", err)
+	}
+	if err := repo.SaveConfigSchema(ctx, "org/ns/name/v2.0.0", "type: string\n"); err != nil {
+		t.Fatalf("SaveConfigSchema: %v", err)
+	}
+
+	properties, err := repo.GetSchemaProperties(ctx, "org/ns/name/v1.0.0")
+	if err != nil {
+		t.Fatalf("GetSchemaProperties: %v", err)
+	}
+	want := []PropertyInfo{
+		{Name: "extra"},
+		{Name: "host", Type: "string"},
+		{Name: "port", Type: "integer", Required: true},
+		{Name: "timeout", Type: "number|null"},
+	}
+	if !slices.Equal(properties, want) {
+		t.Errorf("got %v, want %v", properties, want)
+	}
+
+	properties, err = repo.GetSchemaProperties(ctx, "org/ns/name/v2.0.0")
+	if err != nil || len(properties) != 0 {
+		t.Errorf("no properties block: got %v, %v, want none", properties, err)
+	}
+	if _, err := repo.GetSchemaProperties(ctx, "org/ns/name/v3.0.0"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing: got %v, want ErrSchemaNotFound", err)
+	}
+}
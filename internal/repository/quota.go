@@ -0,0 +1,37 @@
+package repository
+
+import "context"
+
+// checkOrgQuota fails with QuotaExceededError if storing additional bytes
+// would take org over the configured quota.
+func (repo *EtcdRepository) checkOrgQuota(ctx context.Context, org string, additional int64) error {
+	if repo.orgQuota <= 0 {
+		return nil
+	}
+	sizes, err := repo.GetSchemaSizesByPrefix(ctx, repo.getOrganizationPrefix(org))
+	if err != nil {
+		return err
+	}
+	total := additional
+	for _, size := range sizes {
+		total += size
+	}
+	if total > repo.orgQuota {
+		return &QuotaExceededError{Organization: org, Size: total, Limit: repo.orgQuota}
+	}
+	return nil
+}
+
+// checkRewriteQuota is checkOrgQuota for replacing the value under key,
+// previous bytes long, with one of next bytes. Writes that do not grow the
+// value always pass.
+func (repo *EtcdRepository) checkRewriteQuota(ctx context.Context, key string, previous, next int) error {
+	if repo.orgQuota <= 0 || next <= previous {
+		return nil
+	}
+	schemaDetails, err := repo.getSchemaDetailsFromKey(key)
+	if err != nil {
+		return err
+	}
+	return repo.checkOrgQuota(ctx, schemaDetails.GetOrganization(), int64(next-previous))
+}
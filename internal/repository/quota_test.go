@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// quotaForTwo returns a quota that fits two schemas saved with mustSave
+// in one organization, pointers included, but not a third.
+func quotaForTwo(t *testing.T) int64 {
+	t.Helper()
+	repo, fake := newTestRepo(t)
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	size := int64(len(fake.get("org/ns/name/v1.0.0").Value))
+	return 2*size + size/2
+}
+
+func TestOrgQuotaRejectsSaves(t *testing.T) {
+	quota := quotaForTwo(t)
+	repo, _ := newTestRepo(t, WithOrgQuota(quota))
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0")
+
+	err := repo.SaveConfigSchema(context.Background(), "org/ns/name/v1.2.0", testSchema)
+	var exceeded *QuotaExceededError
+	if !errors.As(err, &exceeded) || !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("got %v, want QuotaExceededError", err)
+	}
+	if exceeded.Organization != "org" || exceeded.Limit != quota || exceeded.Size <= quota {
+		t.Errorf("got %+v", exceeded)
+	}
+	// Other organizations have their own quota.
+	mustSave(t, repo, "other/ns/name/v1.0.0")
+}
+
+func TestOrgQuotaIsUnlimitedByDefault(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0")
+}
+
+func TestOrgQuotaCoversEveryWrite(t *testing.T) {
+	quota := quotaForTwo(t)
+	ctx := context.Background()
+	for _, test := range []struct {
+		name  string
+		write func(repo *EtcdRepository) error
+	}{
+		{"move into the organization", func(repo *EtcdRepository) error {
+			return repo.MoveSchema(ctx, "other/ns/name/v1.0.0", "org/ns/moved/v1.0.0")
+		}},
+		{"promote", func(repo *EtcdRepository) error {
+			_, err := repo.PromoteNamespace(ctx, "org", "ns", "v2.0.0")
+			return err
+		}},
+		{"growing patch", func(repo *EtcdRepository) error {
+			patch := `{"description":"` + strings.Repeat("x", int(quota/2)) + `"}`
+			return repo.PatchConfigSchema(ctx, "org/ns/name/v1.0.0", []byte(patch))
+		}},
+		{"label", func(repo *EtcdRepository) error {
+			_, err := repo.AddLabelToPrefix(ctx, "org/ns/", "description", strings.Repeat("x", int(quota/4)))
+			return err
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repo, fake := newTestRepo(t, WithOrgQuota(quota))
+			saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0")
+			mustSave(t, repo, "other/ns/name/v1.0.0")
+			before := fake.currentRevision()
+
+			if err := test.write(repo); !errors.Is(err, ErrQuotaExceeded) {
+				t.Fatalf("got %v, want ErrQuotaExceeded", err)
+			}
+			if fake.currentRevision() != before {
+				t.Errorf("the rejected write reached etcd")
+			}
+		})
+	}
+}
+
+func TestOrgQuotaAllowsShrinkingAndInternalMoves(t *testing.T) {
+	quota := quotaForTwo(t)
+	repo, _ := newTestRepo(t, WithOrgQuota(quota))
+	ctx := context.Background()
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0")
+
+	if err := repo.PatchConfigSchema(ctx, "org/ns/name/v1.0.0", []byte(`{"properties":null}`)); err != nil {
+		t.Errorf("shrinking patch: %v", err)
+	}
+	if err := repo.MoveSchema(ctx, "org/ns/name/v1.1.0", "org/ns/renamed/v1.1.0"); err != nil {
+		t.Errorf("move within the organization: %v", err)
+	}
+}
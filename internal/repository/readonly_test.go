@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadOnlyBlocksMutations(t *testing.T) {
+	writer, fake := newTestRepo(t)
+	mustSave(t, writer, "org/ns/name/v1.0.0")
+	repo, err := NewClient(fake.endpoint(), WithLogger(nil), WithReadOnly())
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(repo.Close)
+	ctx := context.Background()
+	key := "org/ns/name/v1.0.0"
+	before := fake.currentRevision()
+	calls := fake.callCount("Range")
+
+	for name, mutate := range map[string]func() error{
+		"SaveConfigSchema": func() error { return repo.SaveConfigSchema(ctx, "org/ns/name/v2.0.0", testSchema) },
+		"SaveIfLatest": func() error {
+			return repo.SaveIfLatest(ctx, "org", "ns", "name", "v2.0.0", testSchema, "v1.0.0")
+		},
+		"SaveNextPatchVersion": func() error {
+			_, err := repo.SaveNextPatchVersion(ctx, "org", "ns", "name", testSchema)
+			return err
+		},
+		"SaveConfigSchemasBestEffort": func() error {
+			_, err := repo.SaveConfigSchemasBestEffort(ctx, map[string]string{"org/ns/name/v2.0.0": testSchema})
+			return err
+		},
+		"PatchConfigSchema":  func() error { return repo.PatchConfigSchema(ctx, key, []byte(`{}`)) },
+		"ApplyJSONPatch":     func() error { return repo.ApplyJSONPatch(ctx, key, []byte(`[]`)) },
+		"DeleteConfigSchema": func() error { return repo.DeleteConfigSchema(ctx, key) },
+		"DeleteSchemasByPrefix": func() error {
+			_, err := repo.DeleteSchemasByPrefix(ctx, "org/ns/", WithForce())
+			return err
+		},
+		"MoveSchema":       func() error { return repo.MoveSchema(ctx, key, "org/ns/other/v1.0.0") },
+		"RefreshSchemaTTL": func() error { return repo.RefreshSchemaTTL(ctx, key, time.Minute) },
+		"SetActiveVersion": func() error { return repo.SetActiveVersion(ctx, "org", "ns", "name", "v1.0.0") },
+		"SetAlias":         func() error { return repo.SetAlias(ctx, "org", "alias", "org/ns/name") },
+		"DeleteAlias":      func() error { return repo.DeleteAlias(ctx, "org", "alias") },
+		"AddLabelToPrefix": func() error {
+			_, err := repo.AddLabelToPrefix(ctx, "org/ns/", "env", "prod")
+			return err
+		},
+		"PromoteNamespace": func() error {
+			_, err := repo.PromoteNamespace(ctx, "org", "ns", "v3.0.0")
+			return err
+		},
+	} {
+		if err := mutate(); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: got %v, want ErrReadOnly", name, err)
+		}
+	}
+	if fake.currentRevision() != before {
+		t.Errorf("a blocked mutation wrote to etcd")
+	}
+	if reads := fake.callCount("Range") - calls; reads != 0 {
+		t.Errorf("blocked mutations made %d reads", reads)
+	}
+
+	schema, err := repo.GetConfigSchema(ctx, key)
+	if err != nil || schema == nil {
+		t.Errorf("reads: got %v, %v", schema, err)
+	}
+}
@@ -3,80 +3,355 @@ package repository
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"log/slog"
 	"os"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	pb "github.com/jtomic1/config-schema-service/proto"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/mod/semver"
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/protobuf/types/known/timestamppb"
-	"sigs.k8s.io/yaml"
 )
 
+// LatestVersion is not a valid version to save under; it is reserved for
+// resolving the newest version of a schema.
+const LatestVersion = "latest"
+
+const defaultMinDeleteSegments = 2
+
 var (
 	endpoint = os.Getenv("ETCD_ADDRESS")
 	timeout  = 5 * time.Second
 )
 
+// EtcdRepository is safe for concurrent use by multiple goroutines. Its
+// configuration is fixed once NewClient returns; the only state changed
+// afterwards is the client itself, replaced under mu on reconnect along
+// with the count of calls in flight on each client, and the write
+// coalescing group, compiled schema cache and freshness mark, which do
+// their own locking.
 type EtcdRepository struct {
-	client *clientv3.Client
+	mu                sync.RWMutex
+	client            *clientv3.Client
+	clientCalls       map[*clientv3.Client]int
+	config            clientv3.Config
+	kv                clientv3.KV
+	basePrefix        string
+	maxResults        int64
+	maxPageSize       int64
+	iteratorPageSize  int64
+	streamWorkers     int
+	marshal           Marshaler
+	unmarshal         Unmarshaler
+	logger            *slog.Logger
+	tracerProvider    trace.TracerProvider
+	meterProvider     metric.MeterProvider
+	codec             KeyCodec
+	converter         YAMLConverter
+	comparator        VersionComparator
+	segmentPattern    *regexp.Regexp
+	storageFormat     StorageFormat
+	writes            *singleflight.Group
+	schemas           *schemaCache
+	freshness         *freshnessMark
+	maxStaleness      time.Duration
+	orgQuota          int64
+	minDeleteSegments int
+
+	callbackLimit  int
+	callbackPolicy CallbackOverflowPolicy
+
+	tracingDisabled bool
+	readOnly        bool
 }
 
-func NewClient() (*EtcdRepository, error) {
-	cli, err := clientv3.New(clientv3.Config{
-		Endpoints:   []string{endpoint},
-		DialTimeout: timeout,
-	})
-	return &EtcdRepository{
-		client: cli,
-	}, err
+type Marshaler func(schemaData *pb.ConfigSchemaData) ([]byte, error)
+
+type Unmarshaler func(value []byte, schemaData *pb.ConfigSchemaData) error
+
+func defaultMarshaler(schemaData *pb.ConfigSchemaData) ([]byte, error) {
+	return json.Marshal(schemaData)
+}
+
+func defaultUnmarshaler(value []byte, schemaData *pb.ConfigSchemaData) error {
+	return json.Unmarshal(value, schemaData)
+}
+
+func NewClient(opts ...Option) (*EtcdRepository, error) {
+	repo := &EtcdRepository{
+		config: clientv3.Config{
+			Endpoints:   []string{endpoint},
+			DialTimeout: timeout,
+		},
+		maxPageSize:       defaultMaxPageSize,
+		iteratorPageSize:  defaultPageSize,
+		marshal:           defaultMarshaler,
+		unmarshal:         defaultUnmarshaler,
+		logger:            slog.Default(),
+		tracerProvider:    otel.GetTracerProvider(),
+		meterProvider:     otel.GetMeterProvider(),
+		codec:             SlashKeyCodec,
+		converter:         defaultYAMLConverter{},
+		comparator:        semverComparator{},
+		segmentPattern:    DefaultSegmentPattern,
+		schemas:           newSchemaCache(defaultSchemaCacheSize),
+		minDeleteSegments: defaultMinDeleteSegments,
+		clientCalls:       make(map[*clientv3.Client]int),
+	}
+	for _, opt := range opts {
+		opt(repo)
+	}
+	repo.logger = slog.New(correlationHandler{repo.logger.Handler()})
+	cli, err := clientv3.New(repo.config)
+	repo.client = cli
+	repo.kv = &reauthKV{repo: repo}
+	return repo, err
 }
 
 func (repo *EtcdRepository) Close() {
-	repo.client.Close()
+	repo.etcdClient().Close()
 }
 
-func (repo *EtcdRepository) SaveConfigSchema(ctx context.Context, key string, schema string) error {
-	tracer := otel.Tracer("quasar.Repository")
-	ctx, span := tracer.Start(ctx, "Repository.SaveConfigSchema")
+func (repo *EtcdRepository) SaveConfigSchema(ctx context.Context, key string, schema string, opts ...SaveOption) error {
+	ctx, span := repo.startSpan(ctx, "Repository.SaveConfigSchema")
 	defer span.End()
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	return repo.coalescedSave(ctx, key, schema, newSaveOptions(opts))
+}
+
+func (repo *EtcdRepository) saveConfigSchema(ctx context.Context, key, schema string, options *saveOptions) error {
+	schemaDetails, schemaData, serializedData, err := repo.prepareSchemaData(key, schema, options)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withOperationTimeout(ctx)
 	defer cancel()
-	res, err := repo.client.Get(ctx, key)
+	exists, err := repo.SchemaExists(ctx, key)
 	if err != nil {
 		return err
 	}
-	if res.Count > 0 {
-		return errors.New("Key '" + key + "' already exists!")
+	if exists {
+		if options.idempotencyToken != "" {
+			retried, err := repo.isRetriedSave(ctx, key, serializedData)
+			if err != nil || retried {
+				return err
+			}
+		}
+		return &SchemaExistsError{Key: key}
+	}
+	if options.monotonicVersions {
+		prefix := repo.getSchemaPrefix(schemaDetails.GetOrganization(), schemaDetails.GetNamespace(), schemaDetails.GetSchemaName())
+		latest, err := repo.GetLatestVersionByPrefix(ctx, prefix)
+		if err != nil {
+			return err
+		}
+		if latest != "" && repo.comparator.Compare(schemaDetails.GetVersion(), latest) != 1 {
+			return &VersionNotGreaterError{Version: schemaDetails.GetVersion(), Latest: latest}
+		}
+	}
+	if err := repo.checkOrgQuota(ctx, schemaDetails.GetOrganization(), int64(len(serializedData))); err != nil {
+		return err
+	}
+	var putOpts []clientv3.OpOption
+	if options.ttl > 0 {
+		leaseID, err := repo.grantLease(ctx, options.ttl)
+		if err != nil {
+			return err
+		}
+		putOpts = append(putOpts, clientv3.WithLease(leaseID))
 	}
-	schemaJson, err := yaml.YAMLToJSON([]byte(schema))
+	for attempt := 0; attempt < maxModifyAttempts; attempt++ {
+		guard, pointerOps, err := repo.latestPointerUpdate(ctx, schemaDetails)
+		if err != nil {
+			return err
+		}
+		ops := append([]clientv3.Op{clientv3.OpPut(key, string(serializedData), putOpts...)}, pointerOps...)
+		if options.activate {
+			activeKey := repo.getSchemaKey(schemaDetails.GetOrganization(), schemaDetails.GetNamespace(), schemaDetails.GetSchemaName(), ActiveVersion)
+			ops = append(ops, clientv3.OpPut(activeKey, schemaDetails.GetVersion()))
+		}
+		res, err := repo.kv.Txn(ctx).
+			If(append(guard, clientv3.Compare(clientv3.CreateRevision(key), "=", 0))...).
+			Then(append(ops, repo.idIndexOps(key, schemaData.GetSchema())...)...).
+			Commit()
+		if err != nil {
+			return err
+		}
+		if res.Succeeded {
+			return nil
+		}
+		exists, err := repo.SchemaExists(ctx, key)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return &SchemaExistsError{Key: key}
+		}
+	}
+	return ErrConcurrentModification
+}
+
+// isRetriedSave reports whether the schema stored under key was written by
+// an earlier attempt of the same save, i.e. it carries the same idempotency
+// token and schema as serializedData.
+func (repo *EtcdRepository) isRetriedSave(ctx context.Context, key string, serializedData []byte) (bool, error) {
+	res, err := repo.kv.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if len(res.Kvs) == 0 {
+		return false, nil
+	}
+	var stored, attempted pb.ConfigSchemaData
+	if err := repo.decodeSchemaData(res.Kvs[0].Value, &stored); err != nil {
+		return false, err
+	}
+	if err := repo.decodeSchemaData(serializedData, &attempted); err != nil {
+		return false, err
+	}
+	return stored.GetIdempotencyToken() == attempted.GetIdempotencyToken() && stored.GetSchema() == attempted.GetSchema(), nil
+}
+
+func (repo *EtcdRepository) SaveIfLatest(ctx context.Context, org, namespace, name, newVersion, schema, expectedLatest string, opts ...SaveOption) error {
+	ctx, span := repo.startSpan(ctx, "Repository.SaveIfLatest")
+	defer span.End()
+
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	key := repo.getSchemaKey(org, namespace, name, newVersion)
+	options := newSaveOptions(opts)
+	schemaDetails, schemaData, serializedData, err := repo.prepareSchemaData(key, schema, options)
 	if err != nil {
 		return err
 	}
-	schemaData := &pb.ConfigSchemaData{
-		Schema:       string(schemaJson),
-		CreationTime: timestamppb.New(time.Now()),
+	if err := repo.checkOrgQuota(ctx, org, int64(len(serializedData))); err != nil {
+		return err
 	}
-	serializedData, err := json.Marshal(schemaData)
+	prefix := repo.getSchemaPrefix(org, namespace, name)
+	details, revision, err := repo.listSchemaDetails(ctx, prefix)
 	if err != nil {
 		return err
 	}
-	_, err = repo.client.Put(ctx, key, string(serializedData))
-	return err
+	latest := ""
+	if len(details) > 0 {
+		latest = details[len(details)-1].GetVersion()
+	}
+	if latest != expectedLatest {
+		return &LatestMismatchError{Expected: expectedLatest, Actual: latest}
+	}
+	isLatest := latest == "" || repo.comparator.Compare(schemaDetails.GetVersion(), latest) == 1
+	if options.monotonicVersions && !isLatest {
+		return &VersionNotGreaterError{Version: schemaDetails.GetVersion(), Latest: latest}
+	}
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	var putOpts []clientv3.OpOption
+	if options.ttl > 0 {
+		leaseID, err := repo.grantLease(ctx, options.ttl)
+		if err != nil {
+			return err
+		}
+		putOpts = append(putOpts, clientv3.WithLease(leaseID))
+	}
+	// The prefix guard below also covers the pointers, so they are simply
+	// overwritten.
+	ops := []clientv3.Op{clientv3.OpPut(key, string(serializedData), putOpts...)}
+	if isLatest {
+		ops = append(ops, clientv3.OpPut(repo.getSchemaKey(org, namespace, name, latestPointerVersion), schemaDetails.GetVersion()))
+	}
+	if options.activate {
+		ops = append(ops, clientv3.OpPut(repo.getSchemaKey(org, namespace, name, ActiveVersion), schemaDetails.GetVersion()))
+	}
+	res, err := repo.kv.Txn(ctx).
+		If(
+			clientv3.Compare(clientv3.ModRevision(prefix), "<", revision+1).WithPrefix(),
+			clientv3.Compare(clientv3.CreateRevision(key), "=", 0),
+		).
+		Then(append(ops, repo.idIndexOps(key, schemaData.GetSchema())...)...).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !res.Succeeded {
+		latest, err := repo.GetLatestVersionByPrefix(ctx, prefix)
+		if err != nil {
+			return err
+		}
+		return &LatestMismatchError{Expected: expectedLatest, Actual: latest}
+	}
+	return nil
+}
+
+// prepareSchemaData performs every transformation of a schema that can fail
+// (key decoding, YAML conversion, marshaling) so that callers only reach
+// etcd once the value to write is final and never leave a partial write.
+func (repo *EtcdRepository) prepareSchemaData(key, schema string, options *saveOptions) (*pb.ConfigSchemaDetails, *pb.ConfigSchemaData, []byte, error) {
+	schemaDetails, err := repo.getSchemaDetailsFromKey(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if schemaDetails.GetVersion() == LatestVersion || isReservedVersion(schemaDetails.GetVersion()) {
+		return nil, nil, nil, ErrReservedVersion
+	}
+	if err := repo.validateSegments(schemaDetails); err != nil {
+		return nil, nil, nil, err
+	}
+	if strings.TrimSpace(schema) == "" {
+		return nil, nil, nil, ErrEmptySchema
+	}
+	schemaJson, err := repo.converter.YAMLToJSON([]byte(schema))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	if options.minConsumerVersion != "" && !semver.IsValid(options.minConsumerVersion) {
+		return nil, nil, nil, fmt.Errorf("minimum consumer version '%s' is not a valid SemVer string", options.minConsumerVersion)
+	}
+	creationTime := options.creationTime
+	if creationTime.IsZero() {
+		creationTime = time.Now()
+	}
+	schemaData := &pb.ConfigSchemaData{
+		Schema:             string(schemaJson),
+		CreationTime:       timestamppb.New(creationTime),
+		Labels:             options.labels,
+		IdempotencyToken:   options.idempotencyToken,
+		MinConsumerVersion: options.minConsumerVersion,
+		Author:             options.author,
+	}
+	serializedData, err := repo.encodeSchemaData(schemaData)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return schemaDetails, schemaData, serializedData, nil
 }
 
 func (repo *EtcdRepository) GetConfigSchema(ctx context.Context, key string) (*pb.ConfigSchemaData, error) {
-	tracer := otel.Tracer("quasar.Repository")
-	ctx, span := tracer.Start(ctx, "Repository.GetConfigSchema")
+	ctx, span := repo.startSpan(ctx, "Repository.GetConfigSchema")
 	defer span.End()
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	resp, err := repo.client.Get(ctx, key)
+	if err := repo.validateKey(key); err != nil {
+		return nil, err
+	}
+	ctx, cancel := withOperationTimeout(ctx)
+	resp, err := repo.kv.Get(ctx, key)
 	cancel()
 	if err != nil {
 		return nil, err
@@ -85,10 +360,13 @@ func (repo *EtcdRepository) GetConfigSchema(ctx context.Context, key string) (*p
 		return nil, nil
 	}
 	var schemaData pb.ConfigSchemaData
-	if err := json.Unmarshal(resp.Kvs[0].Value, &schemaData); err != nil {
+	if err := repo.decodeSchemaData(resp.Kvs[0].Value, &schemaData); err != nil {
 		return nil, err
 	}
-	schemaYaml, err := yaml.JSONToYAML([]byte(schemaData.GetSchema()))
+	schemaData.IdempotencyToken = ""
+	schemaData.MediaType = ""
+	schemaData.SizeBytes = int64(len(resp.Kvs[0].Value))
+	schemaYaml, err := repo.converter.JSONToYAML([]byte(schemaData.GetSchema()))
 	if err != nil {
 		return nil, err
 	}
@@ -96,80 +374,597 @@ func (repo *EtcdRepository) GetConfigSchema(ctx context.Context, key string) (*p
 	return &schemaData, nil
 }
 
+// GetRaw returns the etcd key-value stored under key as is, with its
+// revisions and lease, or nil if the key does not exist. It is meant for
+// diagnostics and performs no decoding, but key must be a valid schema key.
+func (repo *EtcdRepository) GetRaw(ctx context.Context, key string) (*mvccpb.KeyValue, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetRaw")
+	defer span.End()
+
+	if err := repo.validateKey(key); err != nil {
+		return nil, err
+	}
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, nil
+	}
+	return res.Kvs[0], nil
+}
+
+func (repo *EtcdRepository) SchemaExists(ctx context.Context, key string) (bool, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.SchemaExists")
+	defer span.End()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, key, clientv3.WithCountOnly())
+	if err != nil {
+		return false, err
+	}
+	return res.Count > 0, nil
+}
+
+func (repo *EtcdRepository) MustGetConfigSchema(ctx context.Context, key string) (*pb.ConfigSchemaData, error) {
+	schemaData, err := repo.GetConfigSchema(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if schemaData == nil {
+		return nil, &SchemaNotFoundError{Key: key}
+	}
+	return schemaData, nil
+}
+
+func (repo *EtcdRepository) GetConfigSchemaFull(ctx context.Context, key string) (*pb.ConfigSchema, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetConfigSchemaFull")
+	defer span.End()
+
+	if err := repo.validateKey(key); err != nil {
+		return nil, err
+	}
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, nil
+	}
+	return repo.decodeConfigSchema(res.Kvs[0])
+}
+
 func (repo *EtcdRepository) DeleteConfigSchema(ctx context.Context, key string) error {
-	tracer := otel.Tracer("quasar.Repository")
-	ctx, span := tracer.Start(ctx, "Repository.DeleteConfigSchema")
+	ctx, span := repo.startSpan(ctx, "Repository.DeleteConfigSchema")
 	defer span.End()
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	if err := repo.validateKey(key); err != nil {
+		return err
+	}
+	ctx, cancel := withOperationTimeout(ctx)
 	defer cancel()
-	res, err := repo.client.Delete(ctx, key)
+	res, err := repo.kv.Delete(ctx, key, clientv3.WithPrevKV())
 	if err != nil {
 		return err
 	}
 	if res.Deleted > 0 {
-		return nil
+		if err := repo.unindexDeleted(ctx, res.PrevKvs); err != nil {
+			return err
+		}
+		return repo.refreshLatestPointers(ctx, []string{key})
 	}
-	return errors.New("No schema with key '" + key + "' found!")
+	return &SchemaNotFoundError{Key: key}
 }
 
-func (repo *EtcdRepository) GetSchemasByPrefix(ctx context.Context, prefix string) ([]*pb.ConfigSchema, error) {
-	tracer := otel.Tracer("quasar.Repository")
-	ctx, span := tracer.Start(ctx, "Repository.GetSchemasByPrefix")
+// DeleteSchemasByPrefix deletes every key under prefix. Prefixes with
+// fewer complete segments than the configured minimum are refused with
+// UnsafeDeleteError unless WithForce is passed.
+func (repo *EtcdRepository) DeleteSchemasByPrefix(ctx context.Context, prefix string, opts ...DeleteOption) (int64, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.DeleteSchemasByPrefix")
 	defer span.End()
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	if repo.readOnly {
+		return 0, ErrReadOnly
+	}
+	if prefix == "" {
+		return 0, ErrEmptyPrefix
+	}
+	if segments := repo.prefixSegments(prefix); segments < repo.minDeleteSegments && !newDeleteOptions(opts).force {
+		return 0, &UnsafeDeleteError{Prefix: prefix, Segments: segments, Required: repo.minDeleteSegments}
+	}
+	ctx, cancel := withOperationTimeout(ctx)
 	defer cancel()
-	res, err := repo.client.Get(ctx, prefix, clientv3.WithPrefix())
+	res, err := repo.kv.Delete(ctx, prefix, clientv3.WithPrefix(), clientv3.WithPrevKV())
+	if err != nil {
+		return 0, err
+	}
+	if err := repo.unindexDeleted(ctx, res.PrevKvs); err != nil {
+		return res.Deleted, err
+	}
+	deleted := make([]string, len(res.PrevKvs))
+	for i, kv := range res.PrevKvs {
+		deleted[i] = string(kv.Key)
+	}
+	return res.Deleted, repo.refreshLatestPointers(ctx, deleted)
+}
+
+func (repo *EtcdRepository) DeleteSchemasByPrefixDryRun(ctx context.Context, prefix string) ([]string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.DeleteSchemasByPrefixDryRun")
+	defer span.End()
+
+	if prefix == "" {
+		return nil, ErrEmptyPrefix
+	}
+	return repo.listKeys(ctx, prefix)
+}
+
+// ListKeys returns the schema keys under prefix in ascending order, without
+// transferring or parsing any values. Pointer keys and internal entries are
+// left out.
+func (repo *EtcdRepository) ListKeys(ctx context.Context, prefix string) ([]string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.ListKeys")
+	defer span.End()
+
+	keys, err := repo.listKeys(ctx, prefix)
 	if err != nil {
 		return nil, err
-	} else if res.Count == 0 {
-		return nil, nil
 	}
-	schemas := make([]*pb.ConfigSchema, res.Count)
-	for i, schemaKv := range res.Kvs {
-		schemaDetails := getSchemaDetailsFromKey(string(schemaKv.Key))
-		var schemaData pb.ConfigSchemaData
-		if err := json.Unmarshal(schemaKv.Value, &schemaData); err != nil {
-			return nil, err
+	schemaKeys := keys[:0]
+	for _, key := range keys {
+		if !repo.isReservedKey(key) {
+			schemaKeys = append(schemaKeys, key)
+		}
+	}
+	return schemaKeys, nil
+}
+
+// listKeys returns every raw key under prefix in ascending order, including
+// pointer keys.
+func (repo *EtcdRepository) listKeys(ctx context.Context, prefix string) ([]string, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(res.Kvs))
+	for i, kv := range res.Kvs {
+		keys[i] = string(kv.Key)
+	}
+	return keys, nil
+}
+
+// ListChildren returns the distinct segments that directly follow prefix
+// in the keys under it, up to the next delimiter, in ascending order. With
+// prefix "org/" and delimiter "/" these are the org's namespaces.
+func (repo *EtcdRepository) ListChildren(ctx context.Context, prefix, delimiter string) ([]string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.ListChildren")
+	defer span.End()
+
+	keys, err := repo.ListKeys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	children := []string{}
+	for _, key := range keys {
+		child := strings.TrimPrefix(key, prefix)
+		if delimiter != "" {
+			child, _, _ = strings.Cut(child, delimiter)
+		}
+		if !seen[child] {
+			seen[child] = true
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return children, nil
+}
+
+// GetSchemasByPrefix returns the schemas under prefix, ordered by version
+// unless a list option says otherwise. When nothing matches it returns nil,
+// or an empty slice with WithEmptySlice.
+func (repo *EtcdRepository) GetSchemasByPrefix(ctx context.Context, prefix string, opts ...ListOption) ([]*pb.ConfigSchema, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetSchemasByPrefix")
+	defer span.End()
+
+	options := newListOptions(opts)
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	etcdStart := time.Now()
+	kvs, err := repo.getSchemaKvs(ctx, prefix)
+	span.AddEvent("etcd.get", trace.WithAttributes(
+		attribute.Int64("duration_us", time.Since(etcdStart).Microseconds()),
+	))
+	if err != nil {
+		return nil, err
+	}
+	decodeStart := time.Now()
+	schemas := make([]*pb.ConfigSchema, 0, len(kvs))
+	for _, schemaKv := range kvs {
+		schema, err := repo.decodeStoredSchema(schemaKv, !options.withoutBody)
+		if err != nil && options.skipInvalid {
+			repo.logger.WarnContext(ctx, "skipping invalid entry in prefix scan", "key", string(schemaKv.Key), "error", err)
+			if options.skipped != nil {
+				*options.skipped = append(*options.skipped, string(schemaKv.Key))
+			}
+			continue
 		}
-		schemaYaml, err := yaml.JSONToYAML([]byte(schemaData.GetSchema()))
 		if err != nil {
 			return nil, err
 		}
-		schemaData.Schema = string(schemaYaml)
-		schemas[i] = &pb.ConfigSchema{
-			SchemaDetails: schemaDetails,
-			SchemaData:    &schemaData,
-		}
+		schemas = append(schemas, schema)
 	}
-	sort.Slice(schemas, func(i, j int) bool {
-		return semver.Compare(schemas[i].GetSchemaDetails().GetVersion(), schemas[j].GetSchemaDetails().GetVersion()) == -1
+	span.AddEvent("deserialize", trace.WithAttributes(
+		attribute.Int64("duration_us", time.Since(decodeStart).Microseconds()),
+		attribute.Int("count", len(schemas)),
+	))
+	sort.SliceStable(schemas, func(i, j int) bool {
+		a, b := schemas[i], schemas[j]
+		if options.sortBy == SortByCreationTime {
+			return a.GetSchemaData().GetCreationTime().AsTime().Before(b.GetSchemaData().GetCreationTime().AsTime())
+		}
+		return repo.comparator.Compare(a.GetSchemaDetails().GetVersion(), b.GetSchemaDetails().GetVersion()) == -1
 	})
+	if len(schemas) == 0 {
+		return options.noMatches(), nil
+	}
+	if options.descending {
+		slices.Reverse(schemas)
+	}
 	return schemas, nil
 }
 
+// getSchemaKvs returns the schema entries under prefix, leaving out pointer
+// keys and internal entries. With a result limit configured it reads at most
+// maxResults+1 entries at a time, so only schema keys count towards the
+// limit, and fails with a ResultTooLargeError once the limit is exceeded.
+func (repo *EtcdRepository) getSchemaKvs(ctx context.Context, prefix string) ([]*mvccpb.KeyValue, error) {
+	if repo.maxResults <= 0 {
+		res, err := repo.kv.Get(ctx, prefix, clientv3.WithPrefix())
+		if err != nil {
+			return nil, err
+		}
+		return slices.DeleteFunc(res.Kvs, func(kv *mvccpb.KeyValue) bool {
+			return repo.isReservedKey(string(kv.Key))
+		}), nil
+	}
+
+	rangeEnd := clientv3.GetPrefixRangeEnd(prefix)
+	getOpts := []clientv3.OpOption{clientv3.WithRange(rangeEnd), clientv3.WithLimit(repo.maxResults + 1)}
+	var kvs []*mvccpb.KeyValue
+	var revision int64
+	for key := prefix; ; {
+		res, err := repo.kv.Get(ctx, key, getOpts...)
+		if err != nil {
+			return nil, err
+		}
+		if revision == 0 {
+			revision = res.Header.Revision
+			getOpts = append(getOpts, clientv3.WithRev(revision))
+		}
+		for _, kv := range res.Kvs {
+			if !repo.isReservedKey(string(kv.Key)) {
+				kvs = append(kvs, kv)
+			}
+		}
+		if int64(len(kvs)) > repo.maxResults {
+			return nil, repo.resultTooLarge(ctx, prefix, revision)
+		}
+		if !res.More || len(res.Kvs) == 0 {
+			return kvs, nil
+		}
+		key = string(res.Kvs[len(res.Kvs)-1].Key) + "\x00"
+	}
+}
+
+// resultTooLarge counts the schema keys under prefix at revision, without
+// transferring their values, and reports them against the result limit.
+func (repo *EtcdRepository) resultTooLarge(ctx context.Context, prefix string, revision int64) error {
+	res, err := repo.kv.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly(), clientv3.WithRev(revision))
+	if err != nil {
+		return err
+	}
+	var count int64
+	for _, kv := range res.Kvs {
+		if !repo.isReservedKey(string(kv.Key)) {
+			count++
+		}
+	}
+	return &ResultTooLargeError{Count: count, Limit: repo.maxResults}
+}
+
+// GetSchemasGroupedByNamespace returns the schemas of org keyed by
+// namespace, each group ordered by version.
+func (repo *EtcdRepository) GetSchemasGroupedByNamespace(ctx context.Context, org string) (map[string][]*pb.ConfigSchema, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetSchemasGroupedByNamespace")
+	defer span.End()
+
+	schemas, err := repo.GetSchemasByPrefix(ctx, repo.getOrganizationPrefix(org))
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string][]*pb.ConfigSchema)
+	for _, schema := range schemas {
+		namespace := schema.GetSchemaDetails().GetNamespace()
+		groups[namespace] = append(groups[namespace], schema)
+	}
+	return groups, nil
+}
+
+// GetSchemaSizesByPrefix reports the stored size of every key under prefix.
+// etcd cannot return value lengths without the values themselves, so this
+// still transfers them, but skips all deserialization.
+func (repo *EtcdRepository) GetSchemaSizesByPrefix(ctx context.Context, prefix string) (map[string]int64, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetSchemaSizesByPrefix")
+	defer span.End()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		if repo.isReservedKey(string(kv.Key)) {
+			continue
+		}
+		sizes[string(kv.Key)] = int64(len(kv.Value))
+	}
+	return sizes, nil
+}
+
 func (repo *EtcdRepository) GetLatestVersionByPrefix(ctx context.Context, prefix string) (string, error) {
-	tracer := otel.Tracer("quasar.Repository")
-	ctx, span := tracer.Start(ctx, "Repository.GetLatestVersionByPrefix")
+	ctx, span := repo.startSpan(ctx, "Repository.GetLatestVersionByPrefix")
 	defer span.End()
 
-	schemas, err := repo.GetSchemasByPrefix(ctx, prefix)
+	versions, err := repo.listVersions(ctx, prefix)
 	if err != nil {
 		return "", err
 	}
-	if len(schemas) == 0 {
+	if len(versions) == 0 {
+		return "", nil
+	}
+	return versions[len(versions)-1], nil
+}
+
+// GetLatestVersions resolves the latest version under each prefix. Prefixes
+// nested in another requested prefix are answered from the outer scan;
+// prefixes without versions map to "".
+func (repo *EtcdRepository) GetLatestVersions(ctx context.Context, prefixes []string) (map[string]string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetLatestVersions")
+	defer span.End()
+
+	sorted := append([]string(nil), prefixes...)
+	sort.Strings(sorted)
+	var scans []string
+	for _, prefix := range sorted {
+		if len(scans) > 0 && strings.HasPrefix(prefix, scans[len(scans)-1]) {
+			continue
+		}
+		scans = append(scans, prefix)
+	}
+
+	latest := make(map[string]string, len(prefixes))
+	for _, prefix := range prefixes {
+		latest[prefix] = ""
+	}
+	for _, scan := range scans {
+		details, _, err := repo.listSchemaDetails(ctx, scan)
+		if err != nil {
+			return nil, err
+		}
+		for _, schemaDetails := range details {
+			key := repo.codec.EncodeKey(schemaDetails)
+			version := schemaDetails.GetVersion()
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(key, prefix) && (latest[prefix] == "" || repo.comparator.Compare(version, latest[prefix]) == 1) {
+					latest[prefix] = version
+				}
+			}
+		}
+	}
+	return latest, nil
+}
+
+func (repo *EtcdRepository) GetLatestConfigSchema(ctx context.Context, org, namespace, name string) (*pb.ConfigSchema, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetLatestConfigSchema")
+	defer span.End()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, repo.getSchemaKey(org, namespace, name, latestPointerVersion))
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) > 0 {
+		res, err = repo.kv.Get(ctx, repo.getSchemaKey(org, namespace, name, string(res.Kvs[0].Value)))
+		if err != nil {
+			return nil, err
+		}
+		if len(res.Kvs) > 0 {
+			return repo.decodeConfigSchema(res.Kvs[0])
+		}
+	}
+
+	// No usable pointer: the schema predates pointers or has no versions.
+	prefix := repo.getSchemaPrefix(org, namespace, name)
+	latest, err := repo.GetLatestVersionByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if latest == "" {
+		return nil, &SchemaNotFoundError{Key: prefix, Prefix: true}
+	}
+	res, err = repo.kv.Get(ctx, repo.getSchemaKey(org, namespace, name, latest))
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, ErrConcurrentModification
+	}
+	return repo.decodeConfigSchema(res.Kvs[0])
+}
+
+func (repo *EtcdRepository) GetOldestVersionByPrefix(ctx context.Context, prefix string) (string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetOldestVersionByPrefix")
+	defer span.End()
+
+	versions, err := repo.listVersions(ctx, prefix)
+	if err != nil {
+		return "", err
+	}
+	if len(versions) == 0 {
 		return "", nil
 	}
-	return schemas[len(schemas)-1].GetSchemaDetails().GetVersion(), nil
+	return versions[0], nil
+}
+
+func (repo *EtcdRepository) GetRecentSchemasByPrefix(ctx context.Context, prefix string, n int) ([]*pb.ConfigSchema, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetRecentSchemasByPrefix")
+	defer span.End()
+
+	if n <= 0 {
+		return nil, nil
+	}
+	details, _, err := repo.listSchemaDetails(ctx, prefix)
+	if err != nil {
+		return nil, err
+	} else if len(details) == 0 {
+		return nil, nil
+	}
+	recent := make([]*pb.ConfigSchemaDetails, 0, n)
+	for i := len(details) - 1; i >= 0 && len(recent) < n; i-- {
+		recent = append(recent, details[i])
+	}
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	schemas := make([]*pb.ConfigSchema, 0, len(recent))
+	for _, schemaDetails := range recent {
+		res, err := repo.kv.Get(ctx, repo.codec.EncodeKey(schemaDetails))
+		if err != nil {
+			return nil, err
+		}
+		if len(res.Kvs) == 0 {
+			continue
+		}
+		schema, err := repo.decodeConfigSchema(res.Kvs[0])
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, nil
 }
 
-func getSchemaDetailsFromKey(key string) *pb.ConfigSchemaDetails {
-	tokens := strings.Split(key, "/")
-	return &pb.ConfigSchemaDetails{
-		Organization: tokens[0],
-		Namespace:    tokens[1],
-		SchemaName:   tokens[2],
-		Version:      tokens[3],
+func (repo *EtcdRepository) FindVersionsMatching(ctx context.Context, org, namespace, name, constraintExpr string) ([]string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.FindVersionsMatching")
+	defer span.End()
+
+	versionConstraint, err := parseConstraint(constraintExpr)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := repo.listVersions(ctx, repo.getSchemaPrefix(org, namespace, name))
+	if err != nil {
+		return nil, err
+	}
+	matching := []string{}
+	for _, version := range versions {
+		if versionConstraint.check(version) {
+			matching = append(matching, version)
+		}
+	}
+	return matching, nil
+}
+
+func (repo *EtcdRepository) CountVersionsMatching(ctx context.Context, org, namespace, name, constraintExpr string) (int, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.CountVersionsMatching")
+	defer span.End()
+
+	matching, err := repo.FindVersionsMatching(ctx, org, namespace, name, constraintExpr)
+	if err != nil {
+		return 0, err
+	}
+	return len(matching), nil
+}
+
+func (repo *EtcdRepository) listVersions(ctx context.Context, prefix string) ([]string, error) {
+	details, _, err := repo.listSchemaDetails(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, len(details))
+	for i, schemaDetails := range details {
+		versions[i] = schemaDetails.GetVersion()
+	}
+	return versions, nil
+}
+
+func (repo *EtcdRepository) listSchemaDetails(ctx context.Context, prefix string) ([]*pb.ConfigSchemaDetails, int64, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, 0, err
+	}
+	details := make([]*pb.ConfigSchemaDetails, 0, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		schemaDetails, err := repo.getSchemaDetailsFromKey(string(kv.Key))
+		if err != nil {
+			return nil, 0, err
+		}
+		if isReservedVersion(schemaDetails.GetVersion()) {
+			continue
+		}
+		details = append(details, schemaDetails)
+	}
+	sort.Slice(details, func(i, j int) bool {
+		return repo.comparator.Compare(details[i].GetVersion(), details[j].GetVersion()) == -1
+	})
+	return details, res.Header.Revision, nil
+}
+
+func (repo *EtcdRepository) decodeConfigSchema(kv *mvccpb.KeyValue) (*pb.ConfigSchema, error) {
+	return repo.decodeStoredSchema(kv, true)
+}
+
+func (repo *EtcdRepository) decodeStoredSchema(kv *mvccpb.KeyValue, includeBody bool) (*pb.ConfigSchema, error) {
+	var schemaData pb.ConfigSchemaData
+	if err := repo.decodeSchemaData(kv.Value, &schemaData); err != nil {
+		return nil, err
+	}
+	// The idempotency token only matters to retried saves and the media
+	// type to SchemaMediaType; neither is handed back to readers, whose
+	// bodies are always YAML.
+	schemaData.IdempotencyToken = ""
+	schemaData.MediaType = ""
+	schemaData.SizeBytes = int64(len(kv.Value))
+	if includeBody {
+		schemaYaml, err := repo.converter.JSONToYAML([]byte(schemaData.GetSchema()))
+		if err != nil {
+			return nil, err
+		}
+		schemaData.Schema = string(schemaYaml)
+	} else {
+		schemaData.Schema = ""
+	}
+	schemaDetails, err := repo.getSchemaDetailsFromKey(string(kv.Key))
+	if err != nil {
+		return nil, err
 	}
+	return &pb.ConfigSchema{
+		SchemaDetails: schemaDetails,
+		SchemaData:    &schemaData,
+	}, nil
 }
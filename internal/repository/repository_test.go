@@ -0,0 +1,815 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+const testSchema = "properties:\n  port:\n    type: integer\ntype: object\n"
+
+func mustSave(t testing.TB, repo *EtcdRepository, key string, opts ...SaveOption) {
+	t.Helper()
+	if err := repo.SaveConfigSchema(context.Background(), key, testSchema, opts...); err != nil {
+		t.Fatalf("SaveConfigSchema(%s): %v", key, err)
+	}
+}
+
+func saveVersions(t testing.TB, repo *EtcdRepository, prefix string, versions ...string) {
+	t.Helper()
+	for _, version := range versions {
+		mustSave(t, repo, prefix+version)
+	}
+}
+
+// storedData decodes the value stored under key, which must have been
+// written with the default marshaler.
+func storedData(t testing.TB, fake *fakeEtcd, key string) *pb.ConfigSchemaData {
+	t.Helper()
+	kv := fake.get(key)
+	if kv == nil {
+		t.Fatalf("%s is not stored", key)
+	}
+	var schemaData pb.ConfigSchemaData
+	if err := defaultUnmarshaler(kv.Value, &schemaData); err != nil {
+		t.Fatalf("decoding %s: %v", key, err)
+	}
+	return &schemaData
+}
+
+func schemaVersions(schemas []*pb.ConfigSchema) []string {
+	versions := make([]string, len(schemas))
+	for i, schema := range schemas {
+		versions[i] = schema.GetSchemaDetails().GetVersion()
+	}
+	return versions
+}
+
+func TestGetSchemasByPrefixRejectsTooManyResults(t *testing.T) {
+	repo, _ := newTestRepo(t, WithMaxResults(2))
+	for _, version := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		mustSave(t, repo, "org/ns/name/"+version)
+	}
+
+	_, err := repo.GetSchemasByPrefix(context.Background(), "org/")
+	var tooLarge *ResultTooLargeError
+	if !errors.As(err, &tooLarge) || !errors.Is(err, ErrResultTooLarge) {
+		t.Fatalf("expected ResultTooLargeError, got %v", err)
+	}
+	if tooLarge.Count != 3 || tooLarge.Limit != 2 {
+		t.Errorf("got count %d and limit %d, want 3 and 2", tooLarge.Count, tooLarge.Limit)
+	}
+
+	schemas, err := repo.GetSchemasByPrefix(context.Background(), "org/ns/name/v1.1")
+	if err != nil || len(schemas) != 1 {
+		t.Errorf("narrow prefix: got %d schemas, %v", len(schemas), err)
+	}
+}
+
+func TestGetSchemasByPrefixLimitCountsOnlySchemas(t *testing.T) {
+	repo, _ := newTestRepo(t, WithMaxResults(2))
+	ctx := context.Background()
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0")
+	if err := repo.SetActiveVersion(ctx, "org", "ns", "name", "v1.0.0"); err != nil {
+		t.Fatalf("SetActiveVersion: %v", err)
+	}
+
+	schemas, err := repo.GetSchemasByPrefix(ctx, "org/")
+	if err != nil {
+		t.Fatalf("GetSchemasByPrefix: %v", err)
+	}
+	if got := schemaVersions(schemas); !slices.Equal(got, []string{"v1.0.0", "v1.1.0"}) {
+		t.Errorf("got %v, want both versions", got)
+	}
+}
+
+func TestGetSchemasByPrefixIsUnlimitedByDefault(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	for _, version := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		mustSave(t, repo, "org/ns/name/"+version)
+	}
+	schemas, err := repo.GetSchemasByPrefix(context.Background(), "org/")
+	if err != nil {
+		t.Fatalf("GetSchemasByPrefix: %v", err)
+	}
+	if len(schemas) != 3 {
+		t.Errorf("got %d schemas, want 3", len(schemas))
+	}
+}
+
+func TestGetRecentSchemasByPrefix(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	for _, version := range []string{"v1.2.0", "v1.10.0", "v1.9.0", "v2.0.0"} {
+		mustSave(t, repo, "org/ns/name/"+version)
+	}
+	for _, test := range []struct {
+		n    int
+		want []string
+	}{
+		{2, []string{"v2.0.0", "v1.10.0"}},
+		{4, []string{"v2.0.0", "v1.10.0", "v1.9.0", "v1.2.0"}},
+		{10, []string{"v2.0.0", "v1.10.0", "v1.9.0", "v1.2.0"}},
+	} {
+		schemas, err := repo.GetRecentSchemasByPrefix(context.Background(), "org/ns/name/", test.n)
+		if err != nil {
+			t.Fatalf("n=%d: %v", test.n, err)
+		}
+		if got := schemaVersions(schemas); !slices.Equal(got, test.want) {
+			t.Errorf("n=%d: got %v, want %v", test.n, got, test.want)
+		}
+	}
+}
+
+func TestSaveConfigSchemaRejectsBadBodiesBeforeEtcd(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	for _, test := range []struct {
+		schema string
+		want   error
+	}{
+		{"", ErrEmptySchema},
+		{"  \n", ErrEmptySchema},
+		{"type: [object", ErrInvalidSchema},
+	} {
+		err := repo.SaveConfigSchema(context.Background(), "org/ns/name/v1.0.0", test.schema)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%q: got %v, want %v", test.schema, err, test.want)
+		}
+	}
+	if calls := fake.callCount("Range") + fake.callCount("Txn"); calls != 0 {
+		t.Errorf("rejected saves made %d etcd calls", calls)
+	}
+}
+
+func TestFindVersionsMatching(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.4.2", "v1.10.0", "v2.0.0", "v0.9.0")
+	for _, test := range []struct {
+		constraint string
+		want       []string
+	}{
+		{"^1.2", []string{"v1.4.2", "v1.10.0"}},
+		{"^1.0.0 || >=2", []string{"v1.0.0", "v1.4.2", "v1.10.0", "v2.0.0"}},
+		{">=3.0.0", []string{}},
+	} {
+		versions, err := repo.FindVersionsMatching(context.Background(), "org", "ns", "name", test.constraint)
+		if err != nil {
+			t.Fatalf("%s: %v", test.constraint, err)
+		}
+		if versions == nil || !slices.Equal(versions, test.want) {
+			t.Errorf("%s: got %#v, want %v", test.constraint, versions, test.want)
+		}
+	}
+	if _, err := repo.FindVersionsMatching(context.Background(), "org", "ns", "name", ">= banana"); !errors.Is(err, ErrInvalidConstraint) {
+		t.Errorf("expected ErrInvalidConstraint, got %v", err)
+	}
+}
+
+func TestGetOldestVersionByPrefix(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	oldest, err := repo.GetOldestVersionByPrefix(context.Background(), "org/ns/name/")
+	if err != nil || oldest != "" {
+		t.Fatalf("no versions: got %q, %v", oldest, err)
+	}
+	saveVersions(t, repo, "org/ns/name/", "v1.10.0", "v1.2.0", "v0.10.1", "v0.9.9")
+	oldest, err = repo.GetOldestVersionByPrefix(context.Background(), "org/ns/name/")
+	if err != nil || oldest != "v0.9.9" {
+		t.Errorf("got %q, %v, want v0.9.9", oldest, err)
+	}
+}
+
+func TestMonotonicVersions(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.2.0")
+
+	mustSave(t, repo, "org/ns/name/v1.10.0", WithMonotonicVersions())
+	for _, version := range []string{"v1.9.0", "v1.10.0+build.2"} {
+		err := repo.SaveConfigSchema(context.Background(), "org/ns/name/"+version, testSchema, WithMonotonicVersions())
+		var notGreater *VersionNotGreaterError
+		if !errors.As(err, &notGreater) || !errors.Is(err, ErrVersionNotGreater) {
+			t.Errorf("%s: expected VersionNotGreaterError, got %v", version, err)
+			continue
+		}
+		if notGreater.Latest != "v1.10.0" {
+			t.Errorf("%s: got latest %q, want v1.10.0", version, notGreater.Latest)
+		}
+	}
+	// Without the option backfills stay allowed.
+	mustSave(t, repo, "org/ns/name/v1.9.0")
+}
+
+func TestGetConfigSchemaFull(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	mustSave(t, repo, "org/ns/name/v1.2.3")
+
+	schema, err := repo.GetConfigSchemaFull(context.Background(), "org/ns/name/v1.2.3")
+	if err != nil {
+		t.Fatalf("GetConfigSchemaFull: %v", err)
+	}
+	want := &pb.ConfigSchemaDetails{Organization: "org", Namespace: "ns", SchemaName: "name", Version: "v1.2.3"}
+	if !proto.Equal(schema.GetSchemaDetails(), want) {
+		t.Errorf("got details %v, want %v", schema.GetSchemaDetails(), want)
+	}
+	if schema.GetSchemaData().GetSchema() != testSchema {
+		t.Errorf("got body %q", schema.GetSchemaData().GetSchema())
+	}
+
+	missing, err := repo.GetConfigSchemaFull(context.Background(), "org/ns/name/v9.9.9")
+	if missing != nil || err != nil {
+		t.Errorf("missing schema: got %v, %v", missing, err)
+	}
+}
+
+func TestDeleteSchemasByPrefixDryRun(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0")
+	mustSave(t, repo, "org/other/name/v1.0.0")
+	before := fake.keys()
+
+	keys, err := repo.DeleteSchemasByPrefixDryRun(context.Background(), "org/ns/")
+	if err != nil {
+		t.Fatalf("DeleteSchemasByPrefixDryRun: %v", err)
+	}
+	var want []string
+	for _, key := range before {
+		if strings.HasPrefix(key, "org/ns/") {
+			want = append(want, key)
+		}
+	}
+	if !slices.Equal(keys, want) || !slices.Contains(keys, "org/ns/name/v1.1.0") {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+	if after := fake.keys(); !slices.Equal(after, before) {
+		t.Errorf("the dry run changed the stored keys from %v to %v", before, after)
+	}
+	if _, err := repo.DeleteSchemasByPrefixDryRun(context.Background(), ""); !errors.Is(err, ErrEmptyPrefix) {
+		t.Errorf("empty prefix: got %v, want ErrEmptyPrefix", err)
+	}
+}
+
+func TestSizeBytesMatchesStoredValue(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	key := "org/ns/name/v1.0.0"
+	mustSave(t, repo, key, WithLabels(map[string]string{"team": "platform"}))
+	stored := int64(len(fake.get(key).Value))
+
+	schema, err := repo.GetConfigSchema(context.Background(), key)
+	if err != nil {
+		t.Fatalf("GetConfigSchema: %v", err)
+	}
+	if schema.GetSizeBytes() != stored {
+		t.Errorf("GetConfigSchema: got %d bytes, want %d", schema.GetSizeBytes(), stored)
+	}
+	for _, opts := range [][]ListOption{nil, {WithoutBody()}} {
+		schemas, err := repo.GetSchemasByPrefix(context.Background(), "org/", opts...)
+		if err != nil {
+			t.Fatalf("GetSchemasByPrefix: %v", err)
+		}
+		if size := schemas[0].GetSchemaData().GetSizeBytes(); size != stored {
+			t.Errorf("GetSchemasByPrefix(%d options): got %d bytes, want %d", len(opts), size, stored)
+		}
+	}
+}
+
+func TestMustGetConfigSchema(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	key := "org/ns/name/v1.0.0"
+
+	_, err := repo.MustGetConfigSchema(ctx, key)
+	var notFound *SchemaNotFoundError
+	if !errors.As(err, &notFound) || !errors.Is(err, ErrSchemaNotFound) || notFound.Key != key {
+		t.Errorf("missing key: got %v, want SchemaNotFoundError for %s", err, key)
+	}
+	if exists, err := repo.SchemaExists(ctx, key); exists || err != nil {
+		t.Errorf("SchemaExists on a missing key: got %t, %v", exists, err)
+	}
+
+	mustSave(t, repo, key)
+	schema, err := repo.MustGetConfigSchema(ctx, key)
+	if err != nil || schema.GetSchema() != testSchema {
+		t.Errorf("present key: got %v, %v", schema, err)
+	}
+	if exists, err := repo.SchemaExists(ctx, key); !exists || err != nil {
+		t.Errorf("SchemaExists on a present key: got %t, %v", exists, err)
+	}
+}
+
+func TestSchemaExists(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	for _, test := range []struct {
+		key  string
+		want bool
+	}{
+		{"org/ns/name/v1.0.0", true},
+		{"org/ns/name/v1.0.1", false},
+		{"org/ns/name/v1.0", false},
+		{"org/ns/other/v1.0.0", false},
+	} {
+		exists, err := repo.SchemaExists(context.Background(), test.key)
+		if err != nil || exists != test.want {
+			t.Errorf("%s: got %t, %v, want %t", test.key, exists, err, test.want)
+		}
+	}
+}
+
+func TestSaveWithCreationTime(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	historical := time.Date(2019, 7, 4, 12, 30, 15, 123456789, time.UTC)
+	mustSave(t, repo, "org/ns/name/v1.0.0", WithCreationTime(historical))
+
+	schema, err := repo.GetConfigSchema(ctx, "org/ns/name/v1.0.0")
+	if err != nil {
+		t.Fatalf("GetConfigSchema: %v", err)
+	}
+	if got := schema.GetCreationTime().AsTime(); !got.Equal(historical) {
+		t.Errorf("got %v, want %v", got, historical)
+	}
+
+	before := time.Now()
+	mustSave(t, repo, "org/ns/name/v1.1.0")
+	schema, err = repo.GetConfigSchema(ctx, "org/ns/name/v1.1.0")
+	if err != nil {
+		t.Fatalf("GetConfigSchema: %v", err)
+	}
+	if got := schema.GetCreationTime().AsTime(); got.Before(before) || got.After(time.Now()) {
+		t.Errorf("default creation time %v is not the time of the save", got)
+	}
+}
+
+func TestIdempotencyToken(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	key := "org/ns/name/v1.0.0"
+	mustSave(t, repo, key, WithIdempotencyToken("attempt-1"))
+
+	if err := repo.SaveConfigSchema(ctx, key, testSchema, WithIdempotencyToken("attempt-1")); err != nil {
+		t.Errorf("retry with the same token: %v", err)
+	}
+	var exists *SchemaExistsError
+	for name, err := range map[string]error{
+		"different token":  repo.SaveConfigSchema(ctx, key, testSchema, WithIdempotencyToken("attempt-2")),
+		"no token":         repo.SaveConfigSchema(ctx, key, testSchema),
+		"different schema": repo.SaveConfigSchema(ctx, key, "type: string\n", WithIdempotencyToken("attempt-1")),
+	} {
+		if !errors.As(err, &exists) {
+			t.Errorf("%s: got %v, want SchemaExistsError", name, err)
+		}
+	}
+	if token := storedData(t, fake, key).GetIdempotencyToken(); token != "attempt-1" {
+		t.Errorf("stored token %q, want attempt-1", token)
+	}
+
+	// The token is for retries only and never returned to readers.
+	schemaData, err := repo.GetConfigSchema(ctx, key)
+	if err != nil || schemaData.GetIdempotencyToken() != "" {
+		t.Errorf("GetConfigSchema: got token %q, %v", schemaData.GetIdempotencyToken(), err)
+	}
+	schema, err := repo.GetConfigSchemaFull(ctx, key)
+	if err != nil || schema.GetSchemaData().GetIdempotencyToken() != "" {
+		t.Errorf("GetConfigSchemaFull: got token %q, %v", schema.GetSchemaData().GetIdempotencyToken(), err)
+	}
+	schemas, err := repo.GetSchemasByPrefix(ctx, "org/")
+	if err != nil || len(schemas) != 1 || schemas[0].GetSchemaData().GetIdempotencyToken() != "" {
+		t.Errorf("GetSchemasByPrefix: got %v, %v", schemas, err)
+	}
+}
+
+func TestListKeys(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	saveVersions(t, repo, "org/ns/b/", "v1.0.0")
+	saveVersions(t, repo, "org/ns/a/", "v2.0.0", "v1.0.0")
+	mustSave(t, repo, "other/ns/a/v1.0.0")
+
+	keys, err := repo.ListKeys(context.Background(), "org/ns/")
+	if err != nil {
+		t.Fatalf("ListKeys: %v", err)
+	}
+	want := []string{"org/ns/a/v1.0.0", "org/ns/a/v2.0.0", "org/ns/b/v1.0.0"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+	if !fake.lastRange().GetKeysOnly() {
+		t.Errorf("ListKeys transferred values")
+	}
+}
+
+func TestListKeysSkipsReservedKeys(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	if err := repo.SetActiveVersion(ctx, "org", "ns", "name", "v1.0.0"); err != nil {
+		t.Fatalf("SetActiveVersion: %v", err)
+	}
+	if err := repo.SetAlias(ctx, "org", "pay", "org/ns/name/"); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+	fake.put(probePrefix+"check", "1")
+
+	keys, err := repo.ListKeys(ctx, "")
+	if err != nil {
+		t.Fatalf("ListKeys: %v", err)
+	}
+	if want := []string{"org/ns/name/v1.0.0"}; !slices.Equal(keys, want) {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+}
+
+func TestCountVersionsMatching(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	saveVersions(t, repo, "org/ns/name/", "v0.9.0", "v1.0.0", "v1.4.2", "v1.10.0", "v2.0.0", "v2.1.0")
+	ctx := context.Background()
+	for constraint, want := range map[string]int{"^1": 3, "^1.2": 2, "^2.1": 1, "^3": 0} {
+		count, err := repo.CountVersionsMatching(ctx, "org", "ns", "name", constraint)
+		if err != nil || count != want {
+			t.Errorf("%s: got %d, %v, want %d", constraint, count, err, want)
+		}
+	}
+	if !fake.lastRange().GetKeysOnly() {
+		t.Errorf("CountVersionsMatching transferred values")
+	}
+	if _, err := repo.CountVersionsMatching(ctx, "org", "ns", "name", "^banana"); !errors.Is(err, ErrInvalidConstraint) {
+		t.Errorf("expected ErrInvalidConstraint, got %v", err)
+	}
+}
+
+func TestGetSchemasByPrefixWithoutBody(t *testing.T) {
+	converter := &countingConverter{}
+	repo, _ := newTestRepo(t, WithYAMLConverter(converter))
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	mustSave(t, repo, "org/ns/name/v1.0.0", WithCreationTime(created), WithLabels(map[string]string{"team": "payments"}), WithAuthor("ana"))
+	mustSave(t, repo, "org/ns/name/v1.1.0")
+
+	schemas, err := repo.GetSchemasByPrefix(context.Background(), "org/", WithoutBody())
+	if err != nil {
+		t.Fatalf("GetSchemasByPrefix: %v", err)
+	}
+	if len(schemas) != 2 {
+		t.Fatalf("got %d schemas, want 2", len(schemas))
+	}
+	if converter.toYAML != 0 {
+		t.Errorf("JSONToYAML called %d times, want 0", converter.toYAML)
+	}
+	for _, schema := range schemas {
+		if schema.GetSchemaData().GetSchema() != "" {
+			t.Errorf("%s: body %q returned", schema.GetSchemaDetails().GetVersion(), schema.GetSchemaData().GetSchema())
+		}
+		if schema.GetSchemaData().GetSizeBytes() == 0 || schema.GetSchemaData().GetCreationTime() == nil {
+			t.Errorf("%s: metadata missing: %v", schema.GetSchemaDetails().GetVersion(), schema.GetSchemaData())
+		}
+	}
+	first := schemas[0]
+	if first.GetSchemaDetails().GetVersion() != "v1.0.0" || first.GetSchemaDetails().GetSchemaName() != "name" {
+		t.Errorf("details: got %v", first.GetSchemaDetails())
+	}
+	if data := first.GetSchemaData(); !data.GetCreationTime().AsTime().Equal(created) || data.GetLabels()["team"] != "payments" || data.GetAuthor() != "ana" {
+		t.Errorf("metadata: got %v", data)
+	}
+}
+
+func TestGetSchemasByPrefixSortBy(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// A 1.x hotfix published after 2.0.0 is the newest schema but not the
+	// highest version.
+	mustSave(t, repo, "org/ns/name/v1.0.0", WithCreationTime(base))
+	mustSave(t, repo, "org/ns/name/v2.0.0", WithCreationTime(base.Add(time.Hour)))
+	mustSave(t, repo, "org/ns/name/v1.0.1", WithCreationTime(base.Add(2*time.Hour)))
+
+	for _, test := range []struct {
+		name string
+		opts []ListOption
+		want []string
+	}{
+		{"default", nil, []string{"v1.0.0", "v1.0.1", "v2.0.0"}},
+		{"version descending", []ListOption{WithSortBy(SortByVersion, true)}, []string{"v2.0.0", "v1.0.1", "v1.0.0"}},
+		{"creation time ascending", []ListOption{WithSortBy(SortByCreationTime, false)}, []string{"v1.0.0", "v2.0.0", "v1.0.1"}},
+		{"creation time descending", []ListOption{WithSortBy(SortByCreationTime, true)}, []string{"v1.0.1", "v2.0.0", "v1.0.0"}},
+	} {
+		schemas, err := repo.GetSchemasByPrefix(context.Background(), "org/", test.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var versions []string
+		for _, schema := range schemas {
+			versions = append(versions, schema.GetSchemaDetails().GetVersion())
+		}
+		if !slices.Equal(versions, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, versions, test.want)
+		}
+	}
+}
+
+func TestGetSchemasByPrefixSkipInvalid(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	fake.put("org/ns/name/v2.0.0", "not a schema")
+	fake.put("org/junk", "{}")
+
+	if _, err := repo.GetSchemasByPrefix(ctx, "org/"); err == nil {
+		t.Errorf("strict listing accepted junk entries")
+	}
+
+	var skipped []string
+	schemas, err := repo.GetSchemasByPrefix(ctx, "org/", SkipInvalid(&skipped))
+	if err != nil {
+		t.Fatalf("GetSchemasByPrefix: %v", err)
+	}
+	if len(schemas) != 1 || schemas[0].GetSchemaDetails().GetVersion() != "v1.0.0" {
+		t.Errorf("got %v, want only v1.0.0", schemas)
+	}
+	if want := []string{"org/junk", "org/ns/name/v2.0.0"}; !slices.Equal(skipped, want) {
+		t.Errorf("skipped %v, want %v", skipped, want)
+	}
+
+	if schemas, err := repo.GetSchemasByPrefix(ctx, "org/", SkipInvalid(nil)); err != nil || len(schemas) != 1 {
+		t.Errorf("without a skipped list: got %v, %v", schemas, err)
+	}
+}
+
+func TestListChildren(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	saveVersions(t, repo, "org/payments/card/", "v1.0.0", "v2.0.0")
+	saveVersions(t, repo, "org/payments/wire/", "v1.0.0")
+	mustSave(t, repo, "org/orders/cart/v1.0.0")
+	mustSave(t, repo, "other/users/profile/v1.0.0")
+
+	for _, test := range []struct {
+		prefix string
+		want   []string
+	}{
+		{"org/", []string{"orders", "payments"}},
+		{"org/payments/", []string{"card", "wire"}},
+		{"missing/", []string{}},
+	} {
+		children, err := repo.ListChildren(ctx, test.prefix, "/")
+		if err != nil {
+			t.Fatalf("%s: %v", test.prefix, err)
+		}
+		if children == nil || !slices.Equal(children, test.want) {
+			t.Errorf("%s: got %#v, want %v", test.prefix, children, test.want)
+		}
+	}
+}
+
+func TestListChildrenSkipsReservedKeys(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	if err := repo.SetActiveVersion(ctx, "org", "ns", "name", "v1.0.0"); err != nil {
+		t.Fatalf("SetActiveVersion: %v", err)
+	}
+	if err := repo.SetAlias(ctx, "org", "pay", "org/ns/name/"); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+	fake.put(probePrefix+"check", "1")
+
+	for _, test := range []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{"org"}},
+		{"org/ns/name/", []string{"v1.0.0"}},
+	} {
+		children, err := repo.ListChildren(ctx, test.prefix, "/")
+		if err != nil {
+			t.Fatalf("%q: %v", test.prefix, err)
+		}
+		if !slices.Equal(children, test.want) {
+			t.Errorf("%q: got %v, want %v", test.prefix, children, test.want)
+		}
+	}
+}
+
+func TestGetRaw(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	key := "org/ns/name/v1.0.0"
+	mustSave(t, repo, key, WithTTL(time.Minute))
+	stored := fake.get(key)
+
+	kv, err := repo.GetRaw(ctx, key)
+	if err != nil {
+		t.Fatalf("GetRaw: %v", err)
+	}
+	if string(kv.Key) != key || string(kv.Value) != string(stored.Value) {
+		t.Errorf("got %s = %q, want the stored bytes %q", kv.Key, kv.Value, stored.Value)
+	}
+	if kv.CreateRevision == 0 || kv.ModRevision != stored.ModRevision || kv.Version != 1 || kv.Lease == 0 || kv.Lease != stored.Lease {
+		t.Errorf("metadata: got %+v, want %+v", kv, stored)
+	}
+	if kv, err := repo.GetRaw(ctx, "org/ns/name/v2.0.0"); kv != nil || err != nil {
+		t.Errorf("missing: got %v, %v, want nil", kv, err)
+	}
+}
+
+// TestConcurrentUse is meant for -race: it shares one repository, with
+// every optional piece of mutable state enabled, between goroutines that
+// write, read, validate and watch at once.
+func TestConcurrentUse(t *testing.T) {
+	repo, fake := newTestRepo(t, WithWriteCoalescing(), WithSchemaCacheSize(4), WithMaxStaleness(time.Second), WithCallbackConcurrency(2, CallbackDrop))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mustSave(t, repo, "org/ns/name/v0.0.1")
+	events := repo.WatchSchemas(ctx, "org/")
+	repo.OnChange(ctx, "org/", func(SchemaEvent) {})
+	for fake.callCount("Watch") < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	const workers, rounds = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*rounds*4)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				key := fmt.Sprintf("org/ns/name%d/v1.0.%d", w, i)
+				if err := repo.SaveConfigSchema(ctx, key, testSchema); err != nil {
+					errs <- fmt.Errorf("save %s: %w", key, err)
+				}
+				// Every worker also saves the same key, so coalesced writes
+				// race too.
+				if err := repo.SaveConfigSchema(ctx, "org/ns/shared/v1.0.0", testSchema); err != nil && !errors.As(err, new(*SchemaExistsError)) {
+					errs <- fmt.Errorf("shared save: %w", err)
+				}
+				if _, err := repo.GetConfigSchema(ctx, "org/ns/name/v0.0.1"); err != nil {
+					errs <- fmt.Errorf("get: %w", err)
+				}
+				if _, err := repo.ValidateConfigs(ctx, "org/ns/name/v0.0.1", [][]byte{[]byte("port: 1\n")}); err != nil {
+					errs <- fmt.Errorf("validate: %w", err)
+				}
+				if _, err := repo.GetSchemasByPrefix(ctx, fmt.Sprintf("org/ns/name%d/", w)); err != nil {
+					errs <- fmt.Errorf("list: %w", err)
+				}
+			}
+		}(w)
+	}
+	seen := make(chan int, 1)
+	go func() {
+		puts := 0
+		for event := range events {
+			if event.Type == SchemaPut {
+				puts++
+			}
+			if puts == workers*rounds+1 {
+				break
+			}
+		}
+		seen <- puts
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	select {
+	case <-seen:
+	case <-time.After(10 * time.Second):
+		t.Errorf("the watch did not deliver every save")
+	}
+	schemas, err := repo.GetSchemasByPrefix(ctx, "org/")
+	if err != nil || len(schemas) != workers*rounds+2 {
+		t.Errorf("got %d schemas, %v, want %d", len(schemas), err, workers*rounds+2)
+	}
+}
+
+func TestGetSchemasGroupedByNamespace(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	saveVersions(t, repo, "org/payments/card/", "v1.10.0", "v1.2.0")
+	saveVersions(t, repo, "org/payments/wire/", "v1.0.0")
+	saveVersions(t, repo, "org/orders/cart/", "v2.0.0", "v1.0.0")
+	mustSave(t, repo, "other/users/profile/v1.0.0")
+
+	groups, err := repo.GetSchemasGroupedByNamespace(context.Background(), "org")
+	if err != nil {
+		t.Fatalf("GetSchemasGroupedByNamespace: %v", err)
+	}
+	want := map[string][]string{
+		"payments": {"wire/v1.0.0", "card/v1.2.0", "card/v1.10.0"},
+		"orders":   {"cart/v1.0.0", "cart/v2.0.0"},
+	}
+	if len(groups) != len(want) {
+		t.Errorf("got namespaces %v, want %v", slices.Sorted(maps.Keys(groups)), slices.Sorted(maps.Keys(want)))
+	}
+	for namespace, wantKeys := range want {
+		var keys []string
+		for _, schema := range groups[namespace] {
+			if schema.GetSchemaDetails().GetNamespace() != namespace {
+				t.Errorf("%s: holds a schema of %s", namespace, schema.GetSchemaDetails().GetNamespace())
+			}
+			keys = append(keys, schema.GetSchemaDetails().GetSchemaName()+"/"+schema.GetSchemaDetails().GetVersion())
+		}
+		if !slices.Equal(keys, wantKeys) {
+			t.Errorf("%s: got %v, want %v", namespace, keys, wantKeys)
+		}
+	}
+}
+
+func TestDeleteSchemasByPrefixRequiresForceForBroadPrefixes(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0")
+	mustSave(t, repo, "org/other/name/v1.0.0")
+
+	for _, prefix := range []string{"org/", "org"} {
+		_, err := repo.DeleteSchemasByPrefix(ctx, prefix)
+		var unsafe *UnsafeDeleteError
+		if !errors.As(err, &unsafe) || !errors.Is(err, ErrUnsafeDelete) {
+			t.Errorf("%q: got %v, want UnsafeDeleteError", prefix, err)
+		} else if unsafe.Required != 2 {
+			t.Errorf("%q: required %d segments, want 2", prefix, unsafe.Required)
+		}
+	}
+	if got := fake.callCount("DeleteRange"); got != 0 {
+		t.Errorf("blocked deletes reached etcd %d times", got)
+	}
+
+	if deleted, err := repo.DeleteSchemasByPrefix(ctx, "org/ns/"); err != nil || deleted != 3 {
+		t.Errorf("namespace delete: got %d, %v, want the two versions and the pointer", deleted, err)
+	}
+	if deleted, err := repo.DeleteSchemasByPrefix(ctx, "org/", WithForce()); err != nil || deleted != 2 {
+		t.Errorf("forced org delete: got %d, %v, want 2", deleted, err)
+	}
+	if keys := fake.keys(); len(keys) != 0 {
+		t.Errorf("left %v", keys)
+	}
+
+	strict, _ := newTestRepo(t, WithMinDeleteSegments(3))
+	if _, err := strict.DeleteSchemasByPrefix(ctx, "org/ns/"); !errors.Is(err, ErrUnsafeDelete) {
+		t.Errorf("with three segments required: got %v, want ErrUnsafeDelete", err)
+	}
+}
+
+func TestGetSchemasByPrefixDescending(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	saveVersions(t, repo, "org/ns/name/", "v1.2.0", "v1.10.0", "v0.9.0", "v1.9.0", "v2.0.0-rc.1", "v2.0.0")
+
+	versions := func(opts ...ListOption) []string {
+		t.Helper()
+		schemas, err := repo.GetSchemasByPrefix(ctx, "org/ns/name/", opts...)
+		if err != nil {
+			t.Fatalf("GetSchemasByPrefix: %v", err)
+		}
+		var versions []string
+		for _, schema := range schemas {
+			versions = append(versions, schema.GetSchemaDetails().GetVersion())
+		}
+		return versions
+	}
+	ascending, descending := versions(), versions(Descending())
+	if want := []string{"v0.9.0", "v1.2.0", "v1.9.0", "v1.10.0", "v2.0.0-rc.1", "v2.0.0"}; !slices.Equal(ascending, want) {
+		t.Errorf("ascending: got %v, want %v", ascending, want)
+	}
+	slices.Reverse(ascending)
+	if !slices.Equal(descending, ascending) {
+		t.Errorf("descending: got %v, want %v", descending, ascending)
+	}
+	if latest, err := repo.GetLatestVersionByPrefix(ctx, "org/ns/name/"); err != nil || latest != "v2.0.0" {
+		t.Errorf("GetLatestVersionByPrefix: got %q, %v, want v2.0.0", latest, err)
+	}
+}
+
+func TestGetSchemasByPrefixWithEmptySlice(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	// Neither prefix lists a schema, though the second one holds keys.
+	fake.put("org/ptr/name/"+ActiveVersion, "v1.0.0")
+	fake.put("org/bad/name/v1.0.0", "not a schema")
+
+	for _, prefix := range []string{"org/none/", "org/ptr/", "org/bad/"} {
+		schemas, err := repo.GetSchemasByPrefix(ctx, prefix, SkipInvalid(nil))
+		if err != nil || schemas != nil {
+			t.Errorf("%s: got %v, %v, want nil", prefix, schemas, err)
+		}
+		schemas, err = repo.GetSchemasByPrefix(ctx, prefix, SkipInvalid(nil), WithEmptySlice())
+		if err != nil || schemas == nil || len(schemas) != 0 {
+			t.Errorf("%s with WithEmptySlice: got %#v, %v, want an empty slice", prefix, schemas, err)
+		}
+	}
+	for _, prefix := range []string{"org/none/", "org/ptr/"} {
+		if latest, err := repo.GetLatestVersionByPrefix(ctx, prefix); err != nil || latest != "" {
+			t.Errorf("%s latest: got %q, %v, want none", prefix, latest, err)
+		}
+	}
+
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	if schemas, err := repo.GetSchemasByPrefix(ctx, "org/ns/", WithEmptySlice()); err != nil || len(schemas) != 1 {
+		t.Errorf("matching prefix: got %d schemas, %v, want 1", len(schemas), err)
+	}
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jtomic1/config-schema-service/internal/validators"
+)
+
+type ValidationFailure struct {
+	Key string
+	Err error
+}
+
+// RevalidateAll runs every schema under prefix through the current schema
+// validator and reports the ones that no longer pass. Nothing is modified.
+func (repo *EtcdRepository) RevalidateAll(ctx context.Context, prefix string) ([]ValidationFailure, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.RevalidateAll")
+	defer span.End()
+
+	var failures []ValidationFailure
+	it := repo.IterateSchemasByPrefix(ctx, prefix)
+	for it.Next() {
+		schema := it.Schema()
+		if _, err := validators.IsSchemaValid(schema.GetSchemaData().GetSchema()); err != nil {
+			failures = append(failures, ValidationFailure{
+				Key: repo.codec.EncodeKey(schema.GetSchemaDetails()),
+				Err: err,
+			})
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+func TestRevalidateAll(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	mustSave(t, repo, "org/ns/valid/v1.0.0")
+	// Written behind the repository's back, as if accepted by an older
+	// validator.
+	value, err := defaultMarshaler(&pb.ConfigSchemaData{Schema: `{"type":"no-such-type"}`})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	fake.put("org/ns/invalid/v1.0.0", string(value))
+	before := fake.currentRevision()
+
+	failures, err := repo.RevalidateAll(context.Background(), "org/")
+	if err != nil {
+		t.Fatalf("RevalidateAll: %v", err)
+	}
+	if len(failures) != 1 || failures[0].Key != "org/ns/invalid/v1.0.0" || failures[0].Err == nil {
+		t.Errorf("got %+v, want only org/ns/invalid/v1.0.0", failures)
+	}
+	if fake.currentRevision() != before {
+		t.Errorf("revalidation modified the store")
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const defaultSchemaCacheSize = 64
+
+// schemaCache keeps the most recently used compiled schemas, keyed by the
+// checksum of their canonical JSON. A changed schema has a new checksum,
+// so stale entries are never hit and simply age out.
+type schemaCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+type schemaCacheEntry struct {
+	checksum [sha256.Size]byte
+	schema   *gojsonschema.Schema
+}
+
+func newSchemaCache(size int) *schemaCache {
+	return &schemaCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+func (cache *schemaCache) get(checksum [sha256.Size]byte) *gojsonschema.Schema {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	element, ok := cache.entries[checksum]
+	if !ok {
+		return nil
+	}
+	cache.order.MoveToFront(element)
+	return element.Value.(*schemaCacheEntry).schema
+}
+
+func (cache *schemaCache) add(checksum [sha256.Size]byte, schema *gojsonschema.Schema) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if element, ok := cache.entries[checksum]; ok {
+		cache.order.MoveToFront(element)
+		return
+	}
+	cache.entries[checksum] = cache.order.PushFront(&schemaCacheEntry{checksum: checksum, schema: schema})
+	for cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*schemaCacheEntry).checksum)
+	}
+}
+
+// compileSchema compiles schemaJson, which must be canonical JSON so equal
+// schemas share a cache entry.
+func (repo *EtcdRepository) compileSchema(schemaJson []byte) (*gojsonschema.Schema, error) {
+	checksum := sha256.Sum256(schemaJson)
+	if repo.schemas != nil {
+		if schema := repo.schemas.get(checksum); schema != nil {
+			return schema, nil
+		}
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schemaJson))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	if repo.schemas != nil {
+		repo.schemas.add(checksum, schema)
+	}
+	return schema, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestValidateConfigsCompilesOnce(t *testing.T) {
+	repo, _ := newTestRepo(t, WithSchemaCacheSize(2))
+	ctx := context.Background()
+	key := "org/ns/name/v1.0.0"
+	mustSave(t, repo, key)
+	schemaJson, err := repo.getSchemaJSON(ctx, key)
+	if err != nil {
+		t.Fatalf("getSchemaJSON: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := repo.ValidateConfigs(ctx, key, [][]byte{[]byte("port: 1\n")}); err != nil {
+			t.Fatalf("ValidateConfigs: %v", err)
+		}
+	}
+	if got := repo.schemas.order.Len(); got != 1 {
+		t.Fatalf("cached %d schemas, want 1", got)
+	}
+	compiled := repo.schemas.get(sha256.Sum256(schemaJson))
+	if compiled == nil {
+		t.Fatalf("the schema is not cached under its checksum")
+	}
+	if again, err := repo.compileSchema(schemaJson); err != nil || again != compiled {
+		t.Errorf("compiled again instead of reusing the cached schema")
+	}
+
+	// A changed schema has a new checksum, so it is compiled afresh and
+	// validates with its new rules.
+	if err := repo.PatchConfigSchema(ctx, key, []byte(`{"properties":{"port":{"type":"string"}}}`)); err != nil {
+		t.Fatalf("PatchConfigSchema: %v", err)
+	}
+	results, err := repo.ValidateConfigs(ctx, key, [][]byte{[]byte("port: 1\n")})
+	if err != nil || len(results[0]) != 1 {
+		t.Errorf("after a change: got %v, %v, want the integer port rejected", results, err)
+	}
+	if got := repo.schemas.order.Len(); got != 2 {
+		t.Errorf("cached %d schemas, want 2", got)
+	}
+
+	// The cache is bounded; the least recently used schema goes first.
+	mustSave(t, repo, "org/ns/name/v2.0.0")
+	if err := repo.PatchConfigSchema(ctx, "org/ns/name/v2.0.0", []byte(`{"required":["port"]}`)); err != nil {
+		t.Fatalf("PatchConfigSchema: %v", err)
+	}
+	if _, err := repo.ValidateConfigs(ctx, "org/ns/name/v2.0.0", [][]byte{[]byte("port: 1\n")}); err != nil {
+		t.Fatalf("ValidateConfigs: %v", err)
+	}
+	if got := repo.schemas.order.Len(); got != 2 {
+		t.Errorf("cached %d schemas, want the bound of 2", got)
+	}
+	if repo.schemas.get(sha256.Sum256(schemaJson)) != nil {
+		t.Errorf("the least recently used schema was not evicted")
+	}
+}
+
+func TestSchemaCacheCanBeDisabled(t *testing.T) {
+	repo, _ := newTestRepo(t, WithSchemaCacheSize(0))
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	results, err := repo.ValidateConfigs(context.Background(), "org/ns/name/v1.0.0", [][]byte{[]byte("port: x\n")})
+	if err != nil || len(results[0]) != 1 {
+		t.Errorf("got %v, %v, want the config rejected", results, err)
+	}
+	if repo.schemas != nil {
+		t.Errorf("a cache was kept")
+	}
+}
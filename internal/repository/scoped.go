@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+type ScopedRepository struct {
+	repo         *EtcdRepository
+	organization string
+	namespace    string
+}
+
+func (repo *EtcdRepository) Scoped(org, namespace string) *ScopedRepository {
+	return &ScopedRepository{
+		repo:         repo,
+		organization: org,
+		namespace:    namespace,
+	}
+}
+
+func (scoped *ScopedRepository) Save(ctx context.Context, name, version, schema string) error {
+	return scoped.repo.SaveConfigSchema(ctx, scoped.repo.getSchemaKey(scoped.organization, scoped.namespace, name, version), schema)
+}
+
+func (scoped *ScopedRepository) Get(ctx context.Context, name, version string) (*pb.ConfigSchemaData, error) {
+	return scoped.repo.GetConfigSchema(ctx, scoped.repo.getSchemaKey(scoped.organization, scoped.namespace, name, version))
+}
+
+func (scoped *ScopedRepository) Delete(ctx context.Context, name, version string) error {
+	return scoped.repo.DeleteConfigSchema(ctx, scoped.repo.getSchemaKey(scoped.organization, scoped.namespace, name, version))
+}
+
+func (scoped *ScopedRepository) ListVersions(ctx context.Context, name string) ([]*pb.ConfigSchema, error) {
+	return scoped.repo.GetSchemasByPrefix(ctx, scoped.repo.getSchemaPrefix(scoped.organization, scoped.namespace, name))
+}
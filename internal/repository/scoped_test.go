@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestScopedRepositoryUsesPrefixedKeys(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	scoped := repo.Scoped("org", "ns")
+	ctx := context.Background()
+
+	for _, version := range []string{"v1.0.0", "v1.1.0"} {
+		if err := scoped.Save(ctx, "name", version, testSchema); err != nil {
+			t.Fatalf("Save(%s): %v", version, err)
+		}
+	}
+	for _, key := range []string{"org/ns/name/v1.0.0", "org/ns/name/v1.1.0"} {
+		if fake.get(key) == nil {
+			t.Errorf("%s was not written; stored keys are %v", key, fake.keys())
+		}
+	}
+	if _, err := scoped.Get(ctx, "name", "v1.0.0"); err != nil {
+		t.Errorf("Get: %v", err)
+	}
+	schemas, err := scoped.ListVersions(ctx, "name")
+	if err != nil {
+		t.Fatalf("ListVersions: %v", err)
+	}
+	if got := schemaVersions(schemas); !slices.Equal(got, []string{"v1.0.0", "v1.1.0"}) {
+		t.Errorf("got versions %v", got)
+	}
+
+	if err := scoped.Delete(ctx, "name", "v1.0.0"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if fake.get("org/ns/name/v1.0.0") != nil {
+		t.Errorf("org/ns/name/v1.0.0 survived the delete")
+	}
+	if schema, err := repo.Scoped("org", "other").Get(ctx, "name", "v1.1.0"); schema != nil || err != nil {
+		t.Errorf("another namespace: got %v, %v, want nothing", schema, err)
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// CurrentRevision returns the latest revision of the etcd store, suitable
+// for pinning reads with GetConfigSchemasAtRevision and as the watermark
+// for ChangedSince. It issues an empty transaction, which is always
+// linearizable and touches no key, so neither WithMaxStaleness nor a
+// tenant in ctx can make it return an older revision or fail.
+func (repo *EtcdRepository) CurrentRevision(ctx context.Context) (int64, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.CurrentRevision")
+	defer span.End()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Txn(ctx).Commit()
+	if err != nil {
+		return 0, err
+	}
+	return res.Header.Revision, nil
+}
+
+// GetConfigSchemasAtRevision reads every key as of revision, so the result
+// is a consistent snapshot regardless of concurrent writes. A revision of
+// zero or less pins the current one. The result is aligned with keys and
+// holds nil for keys that did not exist at that revision.
+func (repo *EtcdRepository) GetConfigSchemasAtRevision(ctx context.Context, keys []string, revision int64) ([]*pb.ConfigSchema, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetConfigSchemasAtRevision")
+	defer span.End()
+
+	if revision <= 0 {
+		var err error
+		if revision, err = repo.CurrentRevision(ctx); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	schemas := make([]*pb.ConfigSchema, len(keys))
+	for i, key := range keys {
+		res, err := repo.kv.Get(ctx, key, clientv3.WithRev(revision))
+		if errors.Is(err, rpctypes.ErrCompacted) {
+			return nil, fmt.Errorf("%w: revision %d", ErrRevisionCompacted, revision)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(res.Kvs) == 0 {
+			continue
+		}
+		if schemas[i], err = repo.decodeConfigSchema(res.Kvs[0]); err != nil {
+			return nil, err
+		}
+	}
+	return schemas, nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCurrentRevision(t *testing.T) {
+	repo, fake := newTestRepo(t, WithMaxStaleness(time.Minute))
+	ctx := context.Background()
+	start, err := repo.CurrentRevision(ctx)
+	if err != nil {
+		t.Fatalf("CurrentRevision: %v", err)
+	}
+
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	afterSave, err := repo.CurrentRevision(ctx)
+	if err != nil || afterSave <= start || afterSave != fake.currentRevision() {
+		t.Errorf("after a save: got %d, %v, want %d", afterSave, err, fake.currentRevision())
+	}
+	if _, err := repo.GetConfigSchema(ctx, "org/ns/name/v1.0.0"); err != nil {
+		t.Fatalf("GetConfigSchema: %v", err)
+	}
+	if got, err := repo.CurrentRevision(ctx); err != nil || got != afterSave {
+		t.Errorf("after a read: got %d, %v, want %d", got, err, afterSave)
+	}
+
+	// Neither a lagging replica nor a tenant holds the watermark back.
+	fake.setReplicaRevision(afterSave)
+	written := fake.put("globex/ns/name/v1.0.0", "{}")
+	ranges := fake.callCount("Range")
+	got, err := repo.CurrentRevision(WithTenant(ctx, "acme"))
+	if err != nil || got != written {
+		t.Errorf("after an outside write: got %d, %v, want %d", got, err, written)
+	}
+	if fake.callCount("Range") != ranges {
+		t.Errorf("CurrentRevision read keys")
+	}
+}
+
+func TestGetConfigSchemasAtRevision(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	mustSave(t, repo, "org/ns/a/v1.0.0")
+	mustSave(t, repo, "org/ns/b/v1.0.0")
+	pinned, err := repo.CurrentRevision(ctx)
+	if err != nil || pinned != fake.currentRevision() {
+		t.Fatalf("CurrentRevision: got %d, %v, want %d", pinned, err, fake.currentRevision())
+	}
+
+	if err := repo.PatchConfigSchema(ctx, "org/ns/a/v1.0.0", []byte(`{"required":["port"]}`)); err != nil {
+		t.Fatalf("PatchConfigSchema: %v", err)
+	}
+	if err := repo.DeleteConfigSchema(ctx, "org/ns/b/v1.0.0"); err != nil {
+		t.Fatalf("DeleteConfigSchema: %v", err)
+	}
+	mustSave(t, repo, "org/ns/c/v1.0.0")
+
+	keys := []string{"org/ns/a/v1.0.0", "org/ns/b/v1.0.0", "org/ns/c/v1.0.0"}
+	schemas, err := repo.GetConfigSchemasAtRevision(ctx, keys, pinned)
+	if err != nil {
+		t.Fatalf("GetConfigSchemasAtRevision: %v", err)
+	}
+	if schemas[0].GetSchemaData().GetSchema() != testSchema {
+		t.Errorf("a: got the modified body %q", schemas[0].GetSchemaData().GetSchema())
+	}
+	if schemas[1] == nil {
+		t.Errorf("b: the deleted schema is missing from the snapshot")
+	}
+	if schemas[2] != nil {
+		t.Errorf("c: the later schema is in the snapshot")
+	}
+
+	current, err := repo.GetConfigSchemasAtRevision(ctx, keys, 0)
+	if err != nil {
+		t.Fatalf("current revision: %v", err)
+	}
+	if current[0].GetSchemaData().GetSchema() == testSchema || current[1] != nil || current[2] == nil {
+		t.Errorf("revision 0 did not read the current state")
+	}
+}
+
+func TestGetConfigSchemasAtCompactedRevision(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	mustSave(t, repo, "org/ns/a/v1.0.0")
+	pinned := fake.currentRevision()
+	mustSave(t, repo, "org/ns/a/v2.0.0")
+	if _, err := repo.etcdClient().Compact(ctx, fake.currentRevision()); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	_, err := repo.GetConfigSchemasAtRevision(ctx, []string{"org/ns/a/v1.0.0"}, pinned)
+	if !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("got %v, want ErrRevisionCompacted", err)
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// freshnessMark remembers the newest revision known to have been current
+// on the leader, and when. Any member that has applied at least that
+// revision is at most time.Since(at) behind the leader.
+type freshnessMark struct {
+	mu       sync.Mutex
+	revision int64
+	at       time.Time
+}
+
+// observe records that revision was current no earlier than at, the time
+// the request that returned it was sent.
+func (mark *freshnessMark) observe(revision int64, at time.Time) {
+	mark.mu.Lock()
+	defer mark.mu.Unlock()
+	if revision >= mark.revision && at.After(mark.at) {
+		mark.revision, mark.at = revision, at
+	}
+}
+
+func (mark *freshnessMark) within(bound time.Duration) bool {
+	mark.mu.Lock()
+	defer mark.mu.Unlock()
+	return !mark.at.IsZero() && time.Since(mark.at) <= bound
+}
+
+func (mark *freshnessMark) servable(revision int64, bound time.Duration) bool {
+	mark.mu.Lock()
+	defer mark.mu.Unlock()
+	return !mark.at.IsZero() && revision >= mark.revision && time.Since(mark.at) <= bound
+}
+
+// observeRevision feeds the revision of a linearizable response, sent at
+// start, to the freshness mark when bounded staleness is enabled.
+func (repo *EtcdRepository) observeRevision(header *etcdserverpb.ResponseHeader, start time.Time) {
+	if repo.freshness != nil {
+		repo.freshness.observe(header.GetRevision(), start)
+	}
+}
+
+// boundedStaleGet serves reads under WithMaxStaleness. Linearizable reads
+// and writes record the revision the leader was at when they were sent. A
+// read is first tried as serializable against whichever member the client
+// talks to, and is kept if that member has applied the recorded revision
+// and the record is younger than the bound, which limits how far behind the
+// member can be. Otherwise, including when the record has aged past the
+// bound, the read is repeated linearizably, which also renews the record.
+func (kv *reauthKV) boundedStaleGet(ctx context.Context, key string, opts []clientv3.OpOption) (*clientv3.GetResponse, error) {
+	repo := kv.repo
+	if repo.freshness.within(repo.maxStaleness) {
+		res, err := kv.get(ctx, key, append(opts, clientv3.WithSerializable())...)
+		if err != nil {
+			return nil, err
+		}
+		if repo.freshness.servable(res.Header.GetRevision(), repo.maxStaleness) {
+			return res, nil
+		}
+		repo.logger.DebugContext(ctx, "replica too stale, reading linearizably", "key", key, "revision", res.Header.GetRevision())
+	}
+	start := time.Now()
+	res, err := kv.get(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	repo.freshness.observe(res.Header.GetRevision(), start)
+	return res, nil
+}
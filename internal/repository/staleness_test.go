@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMaxStalenessFallsBackToLinearizableReads(t *testing.T) {
+	repo, fake := newTestRepo(t, WithMaxStaleness(time.Minute))
+	ctx := context.Background()
+	// read fetches key and reports how many ranges that took and whether
+	// the last one was served by a replica.
+	read := func(key string) (found bool, ranges int, serializable bool) {
+		t.Helper()
+		before := fake.callCount("Range")
+		schemaData, err := repo.GetConfigSchema(ctx, key)
+		if err != nil {
+			t.Fatalf("GetConfigSchema(%s): %v", key, err)
+		}
+		return schemaData != nil, fake.callCount("Range") - before, fake.lastRange().GetSerializable()
+	}
+
+	// Nothing has been observed from the leader yet.
+	if _, ranges, serializable := read("org/ns/name/v1.0.0"); ranges != 1 || serializable {
+		t.Errorf("first read: %d ranges, serializable %t, want one linearizable", ranges, serializable)
+	}
+
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	if found, ranges, serializable := read("org/ns/name/v1.0.0"); !found || ranges != 1 || !serializable {
+		t.Errorf("fresh replica: found %t in %d ranges, serializable %t, want one serializable", found, ranges, serializable)
+	}
+
+	// The replica stops applying, so it misses the next write.
+	fake.setReplicaRevision(fake.currentRevision())
+	mustSave(t, repo, "org/ns/name/v1.1.0")
+	if found, ranges, serializable := read("org/ns/name/v1.1.0"); !found || ranges != 2 || serializable {
+		t.Errorf("lagging replica: found %t in %d ranges, last serializable %t, want a linearizable retry", found, ranges, serializable)
+	}
+
+	// Once the last leader revision is older than the bound, reads go
+	// straight to the leader.
+	fake.setReplicaRevision(0)
+	repo.freshness.mu.Lock()
+	repo.freshness.at = repo.freshness.at.Add(-time.Hour)
+	repo.freshness.mu.Unlock()
+	if found, ranges, serializable := read("org/ns/name/v1.0.0"); !found || ranges != 1 || serializable {
+		t.Errorf("aged bound: found %t in %d ranges, serializable %t, want one linearizable", found, ranges, serializable)
+	}
+	if found, ranges, serializable := read("org/ns/name/v1.0.0"); !found || ranges != 1 || !serializable {
+		t.Errorf("after renewal: found %t in %d ranges, serializable %t, want one serializable", found, ranges, serializable)
+	}
+}
+
+func TestLinearizableReadsWithoutMaxStaleness(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	if _, err := repo.GetConfigSchema(context.Background(), "org/ns/name/v1.0.0"); err != nil {
+		t.Fatalf("GetConfigSchema: %v", err)
+	}
+	if fake.lastRange().GetSerializable() {
+		t.Errorf("read was served by a replica without WithMaxStaleness")
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"slices"
+)
+
+// SizeSummary describes the distribution of stored schema sizes in bytes.
+// Percentiles use the nearest-rank method; all fields are zero when there
+// are no schemas.
+type SizeSummary struct {
+	Count int
+	Total int64
+	Min   int64
+	Max   int64
+	P50   int64
+	P90   int64
+	P99   int64
+}
+
+// SizeStats summarizes the stored sizes of the schemas under prefix, as
+// reported by GetSchemaSizesByPrefix.
+func (repo *EtcdRepository) SizeStats(ctx context.Context, prefix string) (SizeSummary, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.SizeStats")
+	defer span.End()
+
+	sizesByKey, err := repo.GetSchemaSizesByPrefix(ctx, prefix)
+	if err != nil {
+		return SizeSummary{}, err
+	}
+	if len(sizesByKey) == 0 {
+		return SizeSummary{}, nil
+	}
+	sizes := make([]int64, 0, len(sizesByKey))
+	var total int64
+	for _, size := range sizesByKey {
+		sizes = append(sizes, size)
+		total += size
+	}
+	slices.Sort(sizes)
+	return SizeSummary{
+		Count: len(sizes),
+		Total: total,
+		Min:   sizes[0],
+		Max:   sizes[len(sizes)-1],
+		P50:   percentile(sizes, 50),
+		P90:   percentile(sizes, 90),
+		P99:   percentile(sizes, 99),
+	}, nil
+}
+
+// percentile returns the nearest-rank p-th percentile of the sorted sizes.
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSizeStats(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	// Sizes are measured on the stored values, so they need not decode.
+	for i := 1; i <= 10; i++ {
+		fake.put(fmt.Sprintf("org/ns/name/v1.%d.0", i), strings.Repeat("x", 10*i))
+	}
+	fake.put("org/ns/name/"+latestPointerVersion, strings.Repeat("x", 1000))
+	fake.put("org/other/name/v1.0.0", strings.Repeat("x", 1000))
+
+	summary, err := repo.SizeStats(ctx, "org/ns/")
+	if err != nil {
+		t.Fatalf("SizeStats: %v", err)
+	}
+	want := SizeSummary{Count: 10, Total: 550, Min: 10, Max: 100, P50: 50, P90: 90, P99: 100}
+	if summary != want {
+		t.Errorf("got %+v, want %+v", summary, want)
+	}
+
+	fake.put("org/one/name/v1.0.0", strings.Repeat("x", 7))
+	summary, err = repo.SizeStats(ctx, "org/one/")
+	if err != nil {
+		t.Fatalf("SizeStats: %v", err)
+	}
+	if want := (SizeSummary{Count: 1, Total: 7, Min: 7, Max: 7, P50: 7, P90: 7, P99: 7}); summary != want {
+		t.Errorf("single schema: got %+v, want %+v", summary, want)
+	}
+
+	summary, err = repo.SizeStats(ctx, "org/none/")
+	if err != nil || summary != (SizeSummary{}) {
+		t.Errorf("empty prefix: got %+v, %v, want a zero summary", summary, err)
+	}
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	pb "github.com/jtomic1/config-schema-service/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// StorageFormat selects how schema bodies are written to etcd. In memory
+// the repository always works with JSON bodies; the format only affects
+// the stored bytes.
+type StorageFormat int
+
+const (
+	StorageJSON StorageFormat = iota
+	// StorageYAML stores bodies as normalized YAML for readable etcd dumps.
+	// Bodies stored as JSON remain readable, as JSON is valid YAML.
+	StorageYAML
+)
+
+// encodeSchemaData serializes schemaData with its body in the storage
+// format, recording the format's media type alongside it.
+func (repo *EtcdRepository) encodeSchemaData(schemaData *pb.ConfigSchemaData) ([]byte, error) {
+	schemaData = proto.Clone(schemaData).(*pb.ConfigSchemaData)
+	schemaData.MediaType = MediaTypeSchemaJSON
+	if repo.storageFormat == StorageYAML {
+		schemaYaml, err := repo.converter.JSONToYAML([]byte(schemaData.GetSchema()))
+		if err != nil {
+			return nil, err
+		}
+		schemaData.Schema = string(schemaYaml)
+		schemaData.MediaType = MediaTypeYAML
+	}
+	return repo.marshal(schemaData)
+}
+
+func (repo *EtcdRepository) decodeSchemaData(value []byte, schemaData *pb.ConfigSchemaData) error {
+	if err := repo.unmarshal(value, schemaData); err != nil {
+		return err
+	}
+	if repo.storageFormat == StorageYAML {
+		schemaJson, err := repo.converter.YAMLToJSON([]byte(schemaData.GetSchema()))
+		if err != nil {
+			return err
+		}
+		schemaData.Schema = string(schemaJson)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+)
+
+var envelope = []byte("envelope:")
+
+func TestCustomMarshalerRoundTrip(t *testing.T) {
+	marshal := func(schemaData *pb.ConfigSchemaData) ([]byte, error) {
+		value, err := defaultMarshaler(schemaData)
+		return append(append([]byte(nil), envelope...), value...), err
+	}
+	unmarshal := func(value []byte, schemaData *pb.ConfigSchemaData) error {
+		if !bytes.HasPrefix(value, envelope) {
+			return errors.New("missing envelope")
+		}
+		return defaultUnmarshaler(bytes.TrimPrefix(value, envelope), schemaData)
+	}
+	repo, fake := newTestRepo(t, WithMarshaler(marshal), WithUnmarshaler(unmarshal))
+	mustSave(t, repo, "org/ns/name/v1.0.0", WithLabels(map[string]string{"team": "a"}))
+
+	stored := fake.get("org/ns/name/v1.0.0")
+	if stored == nil || !bytes.HasPrefix(stored.Value, envelope) {
+		t.Fatalf("stored value is not wrapped: %v", stored)
+	}
+	schemaData, err := repo.GetConfigSchema(context.Background(), "org/ns/name/v1.0.0")
+	if err != nil {
+		t.Fatalf("GetConfigSchema: %v", err)
+	}
+	if schemaData.GetSchema() != testSchema || schemaData.GetLabels()["team"] != "a" {
+		t.Errorf("round trip lost data: %v", schemaData)
+	}
+}
+
+func TestFailedMarshalWritesNothing(t *testing.T) {
+	marshalErr := errors.New("marshal failed")
+	failing := func(*pb.ConfigSchemaData) ([]byte, error) { return nil, marshalErr }
+	repo, fake := newTestRepo(t, WithMarshaler(failing))
+	ctx := context.Background()
+
+	if err := repo.SaveConfigSchema(ctx, "org/ns/name/v1.0.0", testSchema); !errors.Is(err, marshalErr) {
+		t.Errorf("SaveConfigSchema: got %v, want the marshal error", err)
+	}
+	if err := repo.SaveIfLatest(ctx, "org", "ns", "name", "v1.0.0", testSchema, ""); !errors.Is(err, marshalErr) {
+		t.Errorf("SaveIfLatest: got %v, want the marshal error", err)
+	}
+	if _, err := repo.SaveNextPatchVersion(ctx, "org", "ns", "name", testSchema); !errors.Is(err, marshalErr) {
+		t.Errorf("SaveNextPatchVersion: got %v, want the marshal error", err)
+	}
+	for _, method := range []string{"Put", "Txn", "LeaseGrant"} {
+		if calls := fake.callCount(method); calls != 0 {
+			t.Errorf("failed saves made %d %s calls", calls, method)
+		}
+	}
+	if keys := fake.keys(); len(keys) != 0 {
+		t.Errorf("failed saves wrote %v", keys)
+	}
+}
+
+func TestYAMLStorageFormat(t *testing.T) {
+	jsonRepo, fake := newTestRepo(t)
+	yamlRepo, err := NewClient(fake.endpoint(), WithStorageFormat(StorageYAML))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer yamlRepo.Close()
+	ctx := context.Background()
+	mustSave(t, jsonRepo, "org/ns/name/v1.0.0")
+	mustSave(t, yamlRepo, "org/ns/name/v2.0.0")
+
+	if stored := storedData(t, fake, "org/ns/name/v1.0.0").GetSchema(); stored != `{"properties":{"port":{"type":"integer"}},"type":"object"}` {
+		t.Errorf("default format stored %q", stored)
+	}
+	if stored := storedData(t, fake, "org/ns/name/v2.0.0").GetSchema(); stored != testSchema {
+		t.Errorf("YAML format stored %q, want %q", stored, testSchema)
+	}
+
+	// Under the YAML format, bodies stored as JSON stay readable.
+	for _, key := range []string{"org/ns/name/v1.0.0", "org/ns/name/v2.0.0"} {
+		schemaData, err := yamlRepo.GetConfigSchema(ctx, key)
+		if err != nil || schemaData.GetSchema() != testSchema {
+			t.Errorf("%s as YAML: got %q, %v", key, schemaData.GetSchema(), err)
+		}
+		schemaJson, err := yamlRepo.GetConfigSchemaJSON(ctx, key)
+		if err != nil || string(schemaJson) != `{"properties":{"port":{"type":"integer"}},"type":"object"}` {
+			t.Errorf("%s as JSON: got %s, %v", key, schemaJson, err)
+		}
+	}
+	for key, want := range map[string]string{"org/ns/name/v1.0.0": MediaTypeSchemaJSON, "org/ns/name/v2.0.0": MediaTypeYAML} {
+		if mediaType, err := yamlRepo.SchemaMediaType(ctx, key); err != nil || mediaType != want {
+			t.Errorf("%s: media type %q, %v, want %q", key, mediaType, err, want)
+		}
+	}
+}
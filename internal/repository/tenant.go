@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// TenantKey is the context key under which callers store the string id of
+// the tenant a request acts for. When set, every key the repository reads,
+// writes or watches with that context must lie in the organization named
+// by the tenant id; other keys fail with TenantMismatchError.
+const TenantKey contextKey = "tenant"
+
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, TenantKey, tenant)
+}
+
+func Tenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(TenantKey).(string)
+	return tenant
+}
+
+// checkTenant rejects key, a schema key or prefix, unless it lies in the
+// organization of the tenant carried by ctx. The repository's own records
+// pass only within the tenant's organization, see internalKey.
+func (repo *EtcdRepository) checkTenant(ctx context.Context, key string) error {
+	tenant := Tenant(ctx)
+	if tenant == "" {
+		return nil
+	}
+	orgPrefix := repo.getOrganizationPrefix(tenant)
+	for _, prefix := range internalPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			orgPrefix = internalKey(prefix, tenant, "")
+			break
+		}
+	}
+	if !strings.HasPrefix(key, orgPrefix) {
+		return &TenantMismatchError{Tenant: tenant, Key: key}
+	}
+	return nil
+}
+
+func (repo *EtcdRepository) checkTenantTxn(ctx context.Context, cmps []clientv3.Cmp, ops ...[]clientv3.Op) error {
+	for _, cmp := range cmps {
+		if err := repo.checkTenant(ctx, string(cmp.Key)); err != nil {
+			return err
+		}
+	}
+	for _, group := range ops {
+		for _, op := range group {
+			if op.IsTxn() {
+				cmps, thens, elses := op.Txn()
+				if err := repo.checkTenantTxn(ctx, cmps, thens, elses); err != nil {
+					return err
+				}
+			} else if err := repo.checkTenant(ctx, string(op.KeyBytes())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
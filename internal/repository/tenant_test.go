@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := WithTenant(context.Background(), "acme")
+	mustSave(t, repo, "globex/ns/name/v1.0.0")
+
+	if err := repo.SaveConfigSchema(ctx, "acme/ns/name/v1.0.0", testSchema); err != nil {
+		t.Fatalf("same-tenant save: %v", err)
+	}
+	if schemaData, err := repo.GetConfigSchema(ctx, "acme/ns/name/v1.0.0"); err != nil || schemaData == nil {
+		t.Errorf("same-tenant get: got %v, %v", schemaData, err)
+	}
+	if schemas, err := repo.GetSchemasByPrefix(ctx, "acme/"); err != nil || len(schemas) != 1 {
+		t.Errorf("same-tenant list: got %d, %v", len(schemas), err)
+	}
+
+	ranges, txns := fake.callCount("Range"), fake.callCount("Txn")
+	for name, operation := range map[string]func() error{
+		"SaveConfigSchema": func() error {
+			return repo.SaveConfigSchema(ctx, "globex/ns/name/v2.0.0", testSchema)
+		},
+		"GetConfigSchema": func() error {
+			_, err := repo.GetConfigSchema(ctx, "globex/ns/name/v1.0.0")
+			return err
+		},
+		"GetSchemasByPrefix": func() error {
+			_, err := repo.GetSchemasByPrefix(ctx, "")
+			return err
+		},
+		"DeleteConfigSchema": func() error {
+			return repo.DeleteConfigSchema(ctx, "globex/ns/name/v1.0.0")
+		},
+		"MoveSchema": func() error {
+			return repo.MoveSchema(ctx, "acme/ns/name/v1.0.0", "globex/ns/name/v3.0.0")
+		},
+		"WatchSchemas": func() error {
+			return (<-repo.WatchSchemas(ctx, "globex/")).Err
+		},
+	} {
+		var mismatch *TenantMismatchError
+		if err := operation(); !errors.As(err, &mismatch) || !errors.Is(err, ErrTenantMismatch) {
+			t.Errorf("%s: got %v, want TenantMismatchError", name, err)
+		} else if mismatch.Tenant != "acme" {
+			t.Errorf("%s: tenant %q, want acme", name, mismatch.Tenant)
+		}
+	}
+	if fake.callCount("Txn") != txns || fake.get("globex/ns/name/v1.0.0") == nil || fake.get("globex/ns/name/v3.0.0") != nil {
+		t.Errorf("a cross-tenant write reached etcd")
+	}
+	// The only read to reach etcd is MoveSchema's of its source, which the
+	// tenant owns.
+	if got := fake.callCount("Range") - ranges; got != 1 {
+		t.Errorf("got %d reads, want only the move's source read", got)
+	}
+}
+
+func TestTenantCannotJoinCoalescedSave(t *testing.T) {
+	repo, fake := newTestRepo(t, WithWriteCoalescing())
+	arrived, release := fake.hold("Txn")
+	key := "globex/ns/name/v1.0.0"
+
+	leader := make(chan error, 1)
+	go func() { leader <- repo.SaveConfigSchema(context.Background(), key, testSchema) }()
+	<-arrived
+	// The identical save in flight must not succeed on behalf of another
+	// tenant; the check happens before joining it.
+	err := repo.SaveConfigSchema(WithTenant(context.Background(), "acme"), key, testSchema)
+	release()
+	if !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("other tenant: got %v, want ErrTenantMismatch", err)
+	}
+	if err := <-leader; err != nil {
+		t.Errorf("leader: %v", err)
+	}
+}
+
+func TestTenantSchemaIDIndexIsPerOrganization(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	const id = "https://example.com/port.json"
+	acme := WithTenant(context.Background(), "acme")
+	globex := WithTenant(context.Background(), "globex")
+	if err := repo.SaveConfigSchema(acme, "acme/ns/name/v1.0.0", idSchema); err != nil {
+		t.Fatalf("acme save: %v", err)
+	}
+	if err := repo.SaveConfigSchema(globex, "globex/ns/other/v1.0.0", idSchema); err != nil {
+		t.Fatalf("globex save: %v", err)
+	}
+	for org, want := range map[string]string{"acme": "acme/ns/name/v1.0.0", "globex": "globex/ns/other/v1.0.0"} {
+		if kv := fake.get(internalKey(idIndexPrefix, org, id)); kv == nil || string(kv.Value) != want {
+			t.Errorf("%s index entry: got %v, want %s", org, kv, want)
+		}
+	}
+
+	schema, err := repo.GetSchemaByID(acme, "acme", id)
+	if err != nil || schema.GetSchemaDetails().GetOrganization() != "acme" {
+		t.Errorf("own id: got %v, %v", schema.GetSchemaDetails(), err)
+	}
+	if _, err := repo.GetSchemaByID(acme, "globex", id); !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("other organization's id: got %v, want ErrTenantMismatch", err)
+	}
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// TimeoutKey is the context key under which callers store a time.Duration
+// that replaces the default per-operation timeout for repository calls made
+// with that context. Deadlines already set on the context still apply.
+const TimeoutKey contextKey = "timeout"
+
+func WithOperationTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, TimeoutKey, d)
+}
+
+func operationTimeout(ctx context.Context) time.Duration {
+	if d, ok := ctx.Value(TimeoutKey).(time.Duration); ok && d > 0 {
+		return d
+	}
+	return timeout
+}
+
+func withOperationTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, operationTimeout(ctx))
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOperationTimeoutFromContext(t *testing.T) {
+	if got := operationTimeout(context.Background()); got != timeout {
+		t.Errorf("no override: got %v, want the default %v", got, timeout)
+	}
+	ctx := WithOperationTimeout(context.Background(), time.Minute)
+	if got := operationTimeout(ctx); got != time.Minute {
+		t.Errorf("override: got %v, want 1m", got)
+	}
+	if got := operationTimeout(WithOperationTimeout(context.Background(), -time.Second)); got != timeout {
+		t.Errorf("negative override: got %v, want the default %v", got, timeout)
+	}
+
+	start := time.Now()
+	opCtx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	if deadline, _ := opCtx.Deadline(); deadline.Sub(start) < 59*time.Second {
+		t.Errorf("the override was not applied: deadline in %v", deadline.Sub(start))
+	}
+
+	// A closer caller deadline still wins.
+	callerCtx, cancelCaller := context.WithTimeout(ctx, time.Second)
+	defer cancelCaller()
+	opCtx, cancel = withOperationTimeout(callerCtx)
+	defer cancel()
+	if deadline, _ := opCtx.Deadline(); deadline.Sub(start) > 2*time.Second {
+		t.Errorf("the caller deadline was extended to %v", deadline.Sub(start))
+	}
+}
+
+func TestOperationTimeoutAppliesToCalls(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+
+	ctx := WithOperationTimeout(context.Background(), time.Nanosecond)
+	if _, err := repo.GetConfigSchema(ctx, "org/ns/name/v1.0.0"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+	if _, err := repo.GetConfigSchema(context.Background(), "org/ns/name/v1.0.0"); err != nil {
+		t.Errorf("default timeout: %v", err)
+	}
+}
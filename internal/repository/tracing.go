@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type contextKey string
+
+// CorrelationIDKey is the context key under which callers store the string
+// correlation ID of the originating request. Repository spans and log
+// lines carry it as "correlation_id"; when absent, one is generated.
+const CorrelationIDKey contextKey = "correlation-id"
+
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, CorrelationIDKey, id)
+}
+
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(CorrelationIDKey).(string)
+	return id
+}
+
+func newCorrelationID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// correlationHandler adds the correlation ID carried by the context of a
+// log call to its record, so every repository log line made with a
+// *Context method can be traced back to the originating request.
+type correlationHandler struct {
+	slog.Handler
+}
+
+func (handler correlationHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := CorrelationID(ctx); id != "" {
+		record.AddAttrs(slog.String("correlation_id", id))
+	}
+	return handler.Handler.Handle(ctx, record)
+}
+
+func (handler correlationHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return correlationHandler{handler.Handler.WithAttrs(attrs)}
+}
+
+func (handler correlationHandler) WithGroup(name string) slog.Handler {
+	return correlationHandler{handler.Handler.WithGroup(name)}
+}
+
+// startSpan starts a repository span as a child of any span carried by
+// ctx, so callers parent repository spans by passing their own context.
+// With tracing disabled it does no work at all, not even assigning a
+// correlation ID.
+func (repo *EtcdRepository) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	if repo.tracingDisabled {
+		return ctx, noop.Span{}
+	}
+	id := CorrelationID(ctx)
+	if id == "" {
+		id = newCorrelationID()
+		ctx = WithCorrelationID(ctx, id)
+	}
+	repo.logger.DebugContext(ctx, "repository operation started", "operation", name)
+	return repo.tracer().Start(ctx, name,
+		trace.WithAttributes(attribute.String("correlation_id", id)),
+	)
+}
+
+// tracer returns the repository's tracer, a no-op one if no provider is
+// configured.
+func (repo *EtcdRepository) tracer() trace.Tracer {
+	if repo.tracerProvider == nil {
+		return noop.NewTracerProvider().Tracer("quasar.Repository")
+	}
+	return repo.tracerProvider.Tracer("quasar.Repository")
+}
+
+// meter returns the repository's meter, a no-op one if no provider is
+// configured.
+func (repo *EtcdRepository) meter() metric.Meter {
+	if repo.meterProvider == nil {
+		return metricnoop.NewMeterProvider().Meter("quasar.Repository")
+	}
+	return repo.meterProvider.Meter("quasar.Repository")
+}
+
+// recordDeadlineExceeded marks the span carried by ctx as failed when err
+// stems from the operation's deadline, so timeouts stand out from other
+// etcd errors in traces.
+func (repo *EtcdRepository) recordDeadlineExceeded(ctx context.Context, err error) {
+	if !errors.Is(err, context.DeadlineExceeded) && status.Code(err) != grpccodes.DeadlineExceeded {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	span.SetStatus(codes.Error, "deadline exceeded")
+	span.SetAttributes(attribute.Int64("repository.timeout_ms", operationTimeout(ctx).Milliseconds()))
+}
+
+// StartSpanFromGRPC starts a span named name whose parent is the trace
+// context propagated in the incoming gRPC metadata of ctx, using the global
+// propagator. Servers whose gRPC instrumentation does not already extract
+// it can pass the returned context to the repository, so repository spans
+// join the caller's trace.
+func StartSpanFromGRPC(ctx context.Context, name string) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	}
+	return otel.Tracer("quasar.Repository").Start(ctx, name)
+}
+
+// metadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+var _ propagation.TextMapCarrier = metadataCarrier(nil)
+
+func (carrier metadataCarrier) Get(key string) string {
+	values := metadata.MD(carrier).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (carrier metadataCarrier) Set(key, value string) {
+	metadata.MD(carrier).Set(key, value)
+}
+
+func (carrier metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(carrier))
+	for key := range carrier {
+		keys = append(keys, key)
+	}
+	return keys
+}
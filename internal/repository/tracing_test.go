@@ -0,0 +1,432 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func newSpanRecorder() (*tracetest.SpanRecorder, Option) {
+	recorder := tracetest.NewSpanRecorder()
+	return recorder, WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+}
+
+func endedSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	t.Fatalf("no span named %s was recorded", name)
+	return nil
+}
+
+func TestGetSchemasByPrefixRecordsPhaseEvents(t *testing.T) {
+	recorder, withRecorder := newSpanRecorder()
+	repo, _ := newTestRepo(t, withRecorder)
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+
+	if _, err := repo.GetSchemasByPrefix(context.Background(), "org/"); err != nil {
+		t.Fatalf("GetSchemasByPrefix: %v", err)
+	}
+	span := endedSpan(t, recorder, "Repository.GetSchemasByPrefix")
+	durations := make(map[string]bool)
+	for _, event := range span.Events() {
+		for _, attr := range event.Attributes {
+			if attr.Key == "duration_us" {
+				durations[event.Name] = true
+			}
+		}
+	}
+	for _, name := range []string{"etcd.get", "deserialize"} {
+		if !durations[name] {
+			t.Errorf("no %q event with a duration in %v", name, span.Events())
+		}
+	}
+}
+
+func TestStartSpanCarriesCorrelationID(t *testing.T) {
+	recorder, withRecorder := newSpanRecorder()
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo, _ := newTestRepo(t, withRecorder, WithLogger(logger))
+
+	ctx := WithCorrelationID(context.Background(), "request-42")
+	if _, err := repo.SchemaExists(ctx, "org/ns/name/v1.0.0"); err != nil {
+		t.Fatalf("SchemaExists: %v", err)
+	}
+	span := endedSpan(t, recorder, "Repository.SchemaExists")
+	if !hasAttribute(span, "correlation_id", "request-42") {
+		t.Errorf("span attributes %v lack the correlation ID", span.Attributes())
+	}
+	if !strings.Contains(logs.String(), `"correlation_id":"request-42"`) {
+		t.Errorf("log %s lacks the correlation ID", logs.String())
+	}
+}
+
+func TestWarningsCarryCorrelationID(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	repo, fake := newTestRepo(t, WithLogger(logger))
+	fake.put("org/ns/name/v1.0.0", "not a schema")
+
+	ctx := WithCorrelationID(context.Background(), "request-42")
+	if _, err := repo.GetSchemasByPrefix(ctx, "org/", SkipInvalid(nil)); err != nil {
+		t.Fatalf("GetSchemasByPrefix: %v", err)
+	}
+	line := logs.String()
+	if !strings.Contains(line, `"level":"WARN"`) || !strings.Contains(line, `"correlation_id":"request-42"`) {
+		t.Errorf("warning %s lacks the correlation ID", line)
+	}
+}
+
+func TestStartSpanGeneratesCorrelationID(t *testing.T) {
+	recorder, withRecorder := newSpanRecorder()
+	repo, _ := newTestRepo(t, withRecorder)
+
+	if _, err := repo.SchemaExists(context.Background(), "org/ns/name/v1.0.0"); err != nil {
+		t.Fatalf("SchemaExists: %v", err)
+	}
+	span := endedSpan(t, recorder, "Repository.SchemaExists")
+	for _, attr := range span.Attributes() {
+		if attr.Key == "correlation_id" && len(attr.Value.AsString()) == 32 {
+			return
+		}
+	}
+	t.Errorf("span attributes %v lack a generated correlation ID", span.Attributes())
+}
+
+func hasAttribute(span sdktrace.ReadOnlySpan, key, value string) bool {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key && attr.Value.AsString() == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTracingDisabledRecordsNothing(t *testing.T) {
+	recorder, withRecorder := newSpanRecorder()
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo, _ := newTestRepo(t, withRecorder, WithLogger(logger), WithTracingDisabled())
+
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	if _, err := repo.GetConfigSchema(context.Background(), "org/ns/name/v1.0.0"); err != nil {
+		t.Fatalf("GetConfigSchema: %v", err)
+	}
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("recorded %d spans with tracing disabled", len(spans))
+	}
+	if strings.Contains(logs.String(), "repository operation started") {
+		t.Errorf("logged operations with tracing disabled: %s", logs.String())
+	}
+}
+
+func TestDeadlineExceededMarksSpan(t *testing.T) {
+	recorder, withRecorder := newSpanRecorder()
+	repo, _ := newTestRepo(t, withRecorder)
+
+	ctx := WithOperationTimeout(context.Background(), time.Nanosecond)
+	if _, err := repo.GetConfigSchema(ctx, "org/ns/name/v1.0.0"); err == nil {
+		t.Fatalf("expected the read to time out")
+	}
+	span := endedSpan(t, recorder, "Repository.GetConfigSchema")
+	if span.Status().Code != codes.Error || span.Status().Description != "deadline exceeded" {
+		t.Errorf("got status %+v", span.Status())
+	}
+	for _, attr := range span.Attributes() {
+		if attr.Key == "repository.timeout_ms" {
+			return
+		}
+	}
+	t.Errorf("span attributes %v lack the timeout", span.Attributes())
+}
+
+func TestSuccessfulOperationsLeaveSpanStatusUnset(t *testing.T) {
+	recorder, withRecorder := newSpanRecorder()
+	repo, _ := newTestRepo(t, withRecorder)
+
+	if _, err := repo.GetConfigSchema(context.Background(), "org/ns/name/v1.0.0"); err != nil {
+		t.Fatalf("GetConfigSchema: %v", err)
+	}
+	if status := endedSpan(t, recorder, "Repository.GetConfigSchema").Status(); status.Code != codes.Unset {
+		t.Errorf("got status %+v", status)
+	}
+}
+
+const observedSchema = "$id: https://example.com/port.json\nproperties:\n  port:\n    default: 8080\n    type: integer\ntype: object\n"
+
+// TestNilObservability calls every repository method with nil tracer and
+// meter providers, passed as options or left unset, and checks each still
+// does its job.
+func TestStartSpanFromGRPCParentsRepositorySpans(t *testing.T) {
+	recorder, withRecorder := newSpanRecorder()
+	repo, _ := newTestRepo(t, withRecorder)
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// The client's span, as a traceparent header in the incoming metadata.
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	md := metadata.MD{}
+	propagation.TraceContext{}.Inject(trace.ContextWithSpanContext(context.Background(), parent), metadataCarrier(md))
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+
+	ctx, span := StartSpanFromGRPC(ctx, "Server.GetConfigSchema")
+	if _, err := repo.GetConfigSchema(ctx, "org/ns/name/v1.0.0"); err != nil {
+		t.Fatalf("GetConfigSchema: %v", err)
+	}
+	span.End()
+
+	server := endedSpan(t, recorder, "Server.GetConfigSchema")
+	if server.Parent().SpanID() != parent.SpanID() || server.SpanContext().TraceID() != parent.TraceID() {
+		t.Errorf("server span has parent %v in trace %v, want the propagated span", server.Parent().SpanID(), server.SpanContext().TraceID())
+	}
+	repoSpan := endedSpan(t, recorder, "Repository.GetConfigSchema")
+	if repoSpan.Parent().SpanID() != server.SpanContext().SpanID() || repoSpan.SpanContext().TraceID() != parent.TraceID() {
+		t.Errorf("repository span has parent %v in trace %v, want the server span", repoSpan.Parent().SpanID(), repoSpan.SpanContext().TraceID())
+	}
+
+	// Without propagated metadata the span starts a new trace.
+	_, root := StartSpanFromGRPC(context.Background(), "Server.Root")
+	root.End()
+	if got := endedSpan(t, recorder, "Server.Root"); got.Parent().IsValid() {
+		t.Errorf("span without metadata has parent %v", got.Parent())
+	}
+}
+
+func TestNilObservability(t *testing.T) {
+	t.Run("nil options", func(t *testing.T) {
+		repo, fake := newTestRepo(t, WithTracerProvider(nil), WithMeterProvider(nil), WithLogger(nil), WithCallbackConcurrency(1, CallbackDrop))
+		exerciseRepository(t, repo, fake)
+	})
+	t.Run("unset fields", func(t *testing.T) {
+		repo, fake := newTestRepo(t, WithCallbackConcurrency(1, CallbackDrop))
+		repo.tracerProvider, repo.meterProvider = nil, nil
+		exerciseRepository(t, repo, fake)
+	})
+}
+
+func exerciseRepository(t *testing.T, repo *EtcdRepository, fake *fakeEtcd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	check := func(method string, err error, ok bool) {
+		t.Helper()
+		if err != nil {
+			t.Errorf("%s: %v", method, err)
+		} else if !ok {
+			t.Errorf("%s: unexpected result", method)
+		}
+	}
+	key := "org/ns/name/v1.0.0"
+
+	events := repo.WatchSchemas(ctx, "org/ns/name/v")
+	deletes, err := repo.WatchDeletes(ctx, "org/ns/name/")
+	check("WatchDeletes", err, deletes != nil)
+	changes := make(chan SchemaEvent, 100)
+	repo.OnChange(ctx, "org/ns/name/v", func(event SchemaEvent) { changes <- event })
+	for fake.callCount("Watch") < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Writes.
+	check("SaveConfigSchema", repo.SaveConfigSchema(ctx, key, observedSchema, WithMinConsumerVersion("v2.0.0")), true)
+	check("SaveIfLatest", repo.SaveIfLatest(ctx, "org", "ns", "name", "v1.1.0", observedSchema, "v1.0.0"), true)
+	version, err := repo.SaveNextPatchVersion(ctx, "org", "ns", "name", observedSchema)
+	check("SaveNextPatchVersion", err, version == "v1.1.1")
+	version, err = repo.SaveNextMinorVersion(ctx, "org", "ns", "name", observedSchema)
+	check("SaveNextMinorVersion", err, version == "v1.2.0")
+	version, err = repo.SaveNextMajorVersion(ctx, "org", "ns", "name", observedSchema)
+	check("SaveNextMajorVersion", err, version == "v2.0.0")
+	check("PublishConfigSchema", repo.PublishConfigSchema(ctx, "org", "ns", "name", "v2.1.0", observedSchema), true)
+	check("SetActiveVersion", repo.SetActiveVersion(ctx, "org", "ns", "name", "v2.0.0"), true)
+	active, err := repo.GetActiveConfigSchema(ctx, "org", "ns", "name")
+	check("GetActiveConfigSchema", err, active.GetSchemaDetails().GetVersion() == "v2.0.0")
+	result, err := repo.SaveConfigSchemasBestEffort(ctx, map[string]string{"org/other/name/v1.0.0": observedSchema, "org/other/name/v1.1.0": observedSchema})
+	check("SaveConfigSchemasBestEffort", err, len(result.Succeeded) == 2)
+	check("PatchConfigSchema", repo.PatchConfigSchema(ctx, "org/other/name/v1.0.0", []byte(`{"description":"merged"}`)), true)
+	check("ApplyJSONPatch", repo.ApplyJSONPatch(ctx, "org/other/name/v1.0.0", []byte(`[{"op":"add","path":"/title","value":"patched"}]`)), true)
+	check("ModifyConfigSchema", repo.ModifyConfigSchema(ctx, "org/other/name/v1.0.0", func(current *pb.ConfigSchemaData) error {
+		current.Labels = map[string]string{"team": "core"}
+		return nil
+	}), true)
+	labeled, err := repo.AddLabelToPrefix(ctx, "org/other/", "tier", "gold")
+	check("AddLabelToPrefix", err, labeled == 2)
+	check("MoveSchema", repo.MoveSchema(ctx, "org/other/name/v1.1.0", "org/moved/name/v1.1.0"), true)
+	promoted, err := repo.PromoteNamespace(ctx, "org", "other", "v9.0.0")
+	check("PromoteNamespace", err, promoted == 1)
+	mustSave(t, repo, "org/leased/name/v1.0.0", WithTTL(time.Minute))
+	check("RefreshSchemaTTL", repo.RefreshSchemaTTL(ctx, "org/leased/name/v1.0.0", 2*time.Minute), true)
+	ttl, err := repo.GetSchemaTTL(ctx, "org/leased/name/v1.0.0")
+	check("GetSchemaTTL", err, ttl > time.Minute)
+	check("CheckWritable", repo.CheckWritable(ctx), true)
+	check("Defragment", repo.Defragment(ctx), true)
+
+	// Single-schema reads.
+	schemaData, err := repo.GetConfigSchema(ctx, key)
+	check("GetConfigSchema", err, schemaData.GetSchema() == observedSchema)
+	kv, err := repo.GetRaw(ctx, key)
+	check("GetRaw", err, kv != nil && kv.ModRevision > 0)
+	exists, err := repo.SchemaExists(ctx, key)
+	check("SchemaExists", err, exists)
+	schemaData, err = repo.MustGetConfigSchema(ctx, key)
+	check("MustGetConfigSchema", err, schemaData.GetSchema() == observedSchema)
+	schema, err := repo.GetConfigSchemaFull(ctx, key)
+	check("GetConfigSchemaFull", err, schema.GetSchemaDetails().GetVersion() == "v1.0.0")
+	schemaJson, err := repo.GetConfigSchemaJSON(ctx, key)
+	check("GetConfigSchemaJSON", err, strings.Contains(string(schemaJson), `"default":8080`))
+	schemaJson, err = repo.GetConfigSchemaPrettyJSON(ctx, key)
+	check("GetConfigSchemaPrettyJSON", err, strings.Contains(string(schemaJson), "\n"))
+	etag, err := repo.SchemaETag(ctx, key)
+	check("SchemaETag", err, etag != "")
+	mediaType, err := repo.SchemaMediaType(ctx, key)
+	check("SchemaMediaType", err, mediaType == MediaTypeSchemaJSON)
+	draft, err := repo.GetSchemaDraft(ctx, key)
+	check("GetSchemaDraft", err, draft == "")
+	properties, err := repo.GetSchemaProperties(ctx, key)
+	check("GetSchemaProperties", err, len(properties) == 1 && properties[0].Name == "port")
+	config, err := repo.ApplyDefaults(ctx, key, []byte(`{}`))
+	check("ApplyDefaults", err, strings.Contains(string(config), "8080"))
+	validation, err := repo.ValidateConfigs(ctx, key, [][]byte{[]byte(`port: 1`), []byte(`port: x`)})
+	check("ValidateConfigs", err, len(validation) == 2 && len(validation[0]) == 0 && len(validation[1]) > 0)
+	compatible, err := repo.CheckCompatibility(ctx, key, "v2.1.0")
+	check("CheckCompatibility", err, compatible)
+	schema, err = repo.GetSchemaByID(ctx, "org", "https://example.com/port.json")
+	check("GetSchemaByID", err, schema != nil)
+	incompatibilities, err := repo.CheckBackwardCompatible(ctx, "org", "ns", "name", "v1.0.0", "v2.0.0")
+	check("CheckBackwardCompatible", err, len(incompatibilities) == 0)
+	schemaData, read, err := repo.GetConfigSchemaFromEndpoint(ctx, key, fake.addr)
+	check("GetConfigSchemaFromEndpoint", err, schemaData.GetSchema() == observedSchema && read.Revision > 0)
+
+	// Listings.
+	versions := []string{"v1.0.0", "v1.1.0", "v1.1.1", "v1.2.0", "v2.0.0", "v2.1.0"}
+	keys, err := repo.ListKeys(ctx, "org/ns/name/v")
+	check("ListKeys", err, len(keys) == len(versions))
+	children, err := repo.ListChildren(ctx, "org/", "/")
+	check("ListChildren", err, slices.Equal(children, []string{"leased", "moved", "ns", "other"}))
+	schemas, err := repo.GetSchemasByPrefix(ctx, "org/ns/")
+	check("GetSchemasByPrefix", err, len(schemas) == len(versions))
+	groups, err := repo.GetSchemasGroupedByNamespace(ctx, "org")
+	check("GetSchemasGroupedByNamespace", err, len(groups["ns"]) == len(versions))
+	sizes, err := repo.GetSchemaSizesByPrefix(ctx, "org/ns/")
+	check("GetSchemaSizesByPrefix", err, len(sizes) == len(versions))
+	stats, err := repo.SizeStats(ctx, "org/ns/")
+	check("SizeStats", err, stats.Count == len(versions))
+	latest, err := repo.GetLatestVersionByPrefix(ctx, "org/ns/name/")
+	check("GetLatestVersionByPrefix", err, latest == "v2.1.0")
+	latestVersions, err := repo.GetLatestVersions(ctx, []string{"org/ns/name/", "org/other/name/"})
+	check("GetLatestVersions", err, latestVersions["org/ns/name/"] == "v2.1.0" && latestVersions["org/other/name/"] == "v9.0.0")
+	schema, err = repo.GetLatestConfigSchema(ctx, "org", "ns", "name")
+	check("GetLatestConfigSchema", err, schema.GetSchemaDetails().GetVersion() == "v2.1.0")
+	isLatest, err := repo.IsLatestVersion(ctx, "org", "ns", "name", "v2.1.0")
+	check("IsLatestVersion", err, isLatest)
+	oldest, err := repo.GetOldestVersionByPrefix(ctx, "org/ns/name/")
+	check("GetOldestVersionByPrefix", err, oldest == "v1.0.0")
+	schemas, err = repo.GetRecentSchemasByPrefix(ctx, "org/ns/", 2)
+	check("GetRecentSchemasByPrefix", err, len(schemas) == 2)
+	matching, err := repo.FindVersionsMatching(ctx, "org", "ns", "name", "^1")
+	check("FindVersionsMatching", err, len(matching) == 4)
+	count, err := repo.CountVersionsMatching(ctx, "org", "ns", "name", "^2")
+	check("CountVersionsMatching", err, count == 2)
+	history, err := repo.GetVersionHistory(ctx, "org", "ns", "name")
+	check("GetVersionHistory", err, len(history) == len(versions))
+	schemas, token, err := repo.GetSchemasByPrefixPage(ctx, "org/ns/name/v", 4, "")
+	check("GetSchemasByPrefixPage", err, len(schemas) == 4 && token != "")
+	iterated := 0
+	it := repo.IterateSchemasByPrefix(ctx, "org/ns/")
+	for it.Next() {
+		iterated++
+	}
+	check("IterateSchemasByPrefix", it.Err(), iterated == len(versions))
+	streamed := 0
+	stream, streamErrs := repo.StreamAll(ctx, "org/ns/")
+	for range stream {
+		streamed++
+	}
+	check("StreamAll", <-streamErrs, streamed == len(versions))
+	revision, err := repo.CurrentRevision(ctx)
+	check("CurrentRevision", err, revision > 0)
+	changed, cursor, err := repo.ChangedSince(ctx, "org/ns/", 0)
+	check("ChangedSince", err, len(changed) == len(versions) && cursor == revision)
+	snapshot, err := repo.GetConfigSchemasAtRevision(ctx, []string{key, "org/ns/name/v3.0.0"}, revision)
+	check("GetConfigSchemasAtRevision", err, len(snapshot) == 2 && snapshot[0] != nil && snapshot[1] == nil)
+	failures, err := repo.RevalidateAll(ctx, "org/")
+	check("RevalidateAll", err, len(failures) == 0)
+	var exported bytes.Buffer
+	check("Export", repo.Export(ctx, "org/ns/", &exported), exported.Len() > 0)
+	exported.Reset()
+	check("ExportFiltered", repo.ExportFiltered(ctx, "org/other/", ExportFilter{LabelSelector: "tier=gold"}, &exported), exported.Len() > 0)
+	exported.Reset()
+	check("ExportSchemaArchive", repo.ExportSchemaArchive(ctx, "org", "ns", "name", &exported, WithGzip()), exported.Len() > 0)
+
+	// Aliases.
+	check("SetAlias", repo.SetAlias(ctx, "org", "port", "org/ns/name/"), true)
+	target, err := repo.ResolveAlias(ctx, "org", "port")
+	check("ResolveAlias", err, target == "org/ns/name/")
+	schemaData, err = repo.GetConfigSchemaByAlias(ctx, "org", "port", "v1.0.0")
+	check("GetConfigSchemaByAlias", err, schemaData.GetSchema() == observedSchema)
+	schema, err = repo.GetLatestConfigSchemaByAlias(ctx, "org", "port")
+	check("GetLatestConfigSchemaByAlias", err, schema.GetSchemaDetails().GetVersion() == "v2.1.0")
+	check("DeleteAlias", repo.DeleteAlias(ctx, "org", "port"), true)
+
+	// Deletes.
+	check("DeleteConfigSchema", repo.DeleteConfigSchema(ctx, key), true)
+	dryRun, err := repo.DeleteSchemasByPrefixDryRun(ctx, "org/moved/name/")
+	check("DeleteSchemasByPrefixDryRun", err, len(dryRun) > 0)
+	deleted, err := repo.DeleteSchemasByPrefix(ctx, "org/moved/name/")
+	check("DeleteSchemasByPrefix", err, deleted > 0)
+	check("Scoped", nil, repo.Scoped("org", "ns").repo == repo)
+
+	// Every watcher saw the writes and the delete.
+	for {
+		event := nextEvent(t, events)
+		if event.Type == SchemaDeleted && event.Key == key {
+			break
+		}
+	}
+	select {
+	case details := <-deletes:
+		check("WatchDeletes", nil, details.GetVersion() == "v1.0.0")
+	case <-time.After(5 * time.Second):
+		t.Errorf("WatchDeletes: no deletion arrived")
+	}
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Errorf("OnChange: no callback ran")
+	}
+	watchdog := repo.WatchHealth(ctx, time.Hour)
+	select {
+	case healthy := <-watchdog.Changes():
+		check("WatchHealth", nil, healthy && watchdog.Healthy())
+	case <-time.After(5 * time.Second):
+		t.Errorf("WatchHealth: no probe result")
+	}
+
+	cancel()
+	repo.Close()
+}
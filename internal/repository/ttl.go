@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func (repo *EtcdRepository) grantLease(ctx context.Context, ttl time.Duration) (clientv3.LeaseID, error) {
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	var leaseID clientv3.LeaseID
+	err := repo.withReauth(ctx, func(cli *clientv3.Client) error {
+		res, err := cli.Grant(ctx, seconds)
+		if err != nil {
+			return err
+		}
+		leaseID = res.ID
+		return nil
+	})
+	return leaseID, err
+}
+
+// GetSchemaTTL reports how long the schema under key has left before its
+// lease expires. Schemas saved without a TTL report zero.
+func (repo *EtcdRepository) GetSchemaTTL(ctx context.Context, key string) (time.Duration, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.GetSchemaTTL")
+	defer span.End()
+
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, key, clientv3.WithKeysOnly())
+	if err != nil {
+		return 0, err
+	}
+	if len(res.Kvs) == 0 {
+		return 0, &SchemaNotFoundError{Key: key}
+	}
+	if res.Kvs[0].Lease == 0 {
+		return 0, nil
+	}
+	var remaining int64
+	err = repo.withReauth(ctx, func(cli *clientv3.Client) error {
+		ttlRes, err := cli.TimeToLive(ctx, clientv3.LeaseID(res.Kvs[0].Lease))
+		if err != nil {
+			return err
+		}
+		remaining = ttlRes.TTL
+		return nil
+	})
+	if err != nil || remaining <= 0 {
+		return 0, err
+	}
+	return time.Duration(remaining) * time.Second, nil
+}
+
+// RefreshSchemaTTL restarts the expiry of the leased schema under key. If
+// ttl equals the lease's granted TTL the lease is kept alive once;
+// otherwise the schema is moved to a new lease of ttl, as etcd cannot
+// change the TTL of an existing lease.
+func (repo *EtcdRepository) RefreshSchemaTTL(ctx context.Context, key string, ttl time.Duration) error {
+	ctx, span := repo.startSpan(ctx, "Repository.RefreshSchemaTTL")
+	defer span.End()
+
+	if repo.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	res, err := repo.kv.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if len(res.Kvs) == 0 {
+		return &SchemaNotFoundError{Key: key}
+	}
+	leaseID := clientv3.LeaseID(res.Kvs[0].Lease)
+	if leaseID == clientv3.NoLease {
+		return fmt.Errorf("%w: '%s'", ErrNotLeased, key)
+	}
+
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	var granted int64
+	err = repo.withReauth(ctx, func(cli *clientv3.Client) error {
+		ttlRes, err := cli.TimeToLive(ctx, leaseID)
+		if err != nil {
+			return err
+		}
+		granted = ttlRes.GrantedTTL
+		if granted != seconds {
+			return nil
+		}
+		_, err = cli.KeepAliveOnce(ctx, leaseID)
+		return err
+	})
+	if err != nil || granted == seconds {
+		return err
+	}
+
+	newLeaseID, err := repo.grantLease(ctx, ttl)
+	if err != nil {
+		return err
+	}
+	txnRes, err := repo.kv.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", res.Kvs[0].ModRevision)).
+		Then(clientv3.OpPut(key, string(res.Kvs[0].Value), clientv3.WithLease(newLeaseID))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txnRes.Succeeded {
+		return ErrConcurrentModification
+	}
+	return nil
+}
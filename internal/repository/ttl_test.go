@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetSchemaTTL(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	mustSave(t, repo, "org/ns/name/v1.0.0", WithTTL(time.Minute))
+	mustSave(t, repo, "org/ns/name/v2.0.0")
+
+	remaining, err := repo.GetSchemaTTL(ctx, "org/ns/name/v1.0.0")
+	if err != nil || remaining <= 0 || remaining > time.Minute {
+		t.Errorf("leased: got %v, %v, want up to a minute", remaining, err)
+	}
+	if remaining, err := repo.GetSchemaTTL(ctx, "org/ns/name/v2.0.0"); remaining != 0 || err != nil {
+		t.Errorf("not leased: got %v, %v, want zero", remaining, err)
+	}
+	if _, err := repo.GetSchemaTTL(ctx, "org/ns/name/v3.0.0"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing: got %v, want ErrSchemaNotFound", err)
+	}
+
+	fake.expireLease(fake.get("org/ns/name/v1.0.0").Lease)
+	if _, err := repo.GetSchemaTTL(ctx, "org/ns/name/v1.0.0"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expired: got %v, want ErrSchemaNotFound", err)
+	}
+}
+
+func TestRefreshSchemaTTL(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	key := "org/ns/name/v1.0.0"
+	mustSave(t, repo, key, WithTTL(2*time.Second))
+	lease := fake.get(key).Lease
+
+	// Same TTL: the lease is kept alive, so the schema outlives the
+	// original two seconds.
+	fake.ageLease(lease, 1500*time.Millisecond)
+	if err := repo.RefreshSchemaTTL(ctx, key, 2*time.Second); err != nil {
+		t.Fatalf("RefreshSchemaTTL: %v", err)
+	}
+	fake.ageLease(lease, time.Second)
+	if schema, err := repo.GetConfigSchema(ctx, key); schema == nil || err != nil {
+		t.Fatalf("the schema did not outlive its original TTL: %v, %v", schema, err)
+	}
+	if kv := fake.get(key); kv.Lease != lease {
+		t.Errorf("the schema moved from lease %d to %d", lease, kv.Lease)
+	}
+
+	// Different TTL: the schema moves to a new lease.
+	if err := repo.RefreshSchemaTTL(ctx, key, time.Minute); err != nil {
+		t.Fatalf("RefreshSchemaTTL: %v", err)
+	}
+	if kv := fake.get(key); kv == nil || kv.Lease == lease {
+		t.Fatalf("the schema was not moved to a new lease: %v", kv)
+	}
+	remaining, err := repo.GetSchemaTTL(ctx, key)
+	if err != nil || remaining <= 2*time.Second || remaining > time.Minute {
+		t.Errorf("got %v, %v, want close to a minute", remaining, err)
+	}
+
+	mustSave(t, repo, "org/ns/name/v2.0.0")
+	if err := repo.RefreshSchemaTTL(ctx, "org/ns/name/v2.0.0", time.Minute); !errors.Is(err, ErrNotLeased) {
+		t.Errorf("not leased: got %v, want ErrNotLeased", err)
+	}
+	if err := repo.RefreshSchemaTTL(ctx, "org/ns/name/v3.0.0", time.Minute); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing: got %v, want ErrSchemaNotFound", err)
+	}
+}
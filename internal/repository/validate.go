@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+type ValidationError struct {
+	Field       string
+	Description string
+}
+
+// ValidateConfigs validates every config (YAML or JSON) against the schema
+// under key. The compiled schema is cached by checksum and reused across
+// calls while it is unchanged. The result holds the errors of each config
+// at the same index; an empty list means the config is valid.
+func (repo *EtcdRepository) ValidateConfigs(ctx context.Context, key string, configs [][]byte) ([][]ValidationError, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.ValidateConfigs")
+	defer span.End()
+
+	schemaJson, err := repo.getSchemaJSON(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := repo.compileSchema(schemaJson)
+	if err != nil {
+		return nil, err
+	}
+	results := make([][]ValidationError, len(configs))
+	for i, config := range configs {
+		configJson, err := repo.converter.YAMLToJSON(config)
+		if err != nil {
+			results[i] = []ValidationError{{Field: "(root)", Description: fmt.Sprintf("%v: %v", ErrInvalidConfig, err)}}
+			continue
+		}
+		result, err := schema.Validate(gojsonschema.NewBytesLoader(configJson))
+		if err != nil {
+			results[i] = []ValidationError{{Field: "(root)", Description: fmt.Sprintf("%v: %v", ErrInvalidConfig, err)}}
+			continue
+		}
+		results[i] = []ValidationError{}
+		for _, resultError := range result.Errors() {
+			results[i] = append(results[i], ValidationError{Field: resultError.Field(), Description: resultError.Description()})
+		}
+	}
+	return results, nil
+}
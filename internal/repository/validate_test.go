@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigs(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	key := "org/ns/name/v1.0.0"
+	schema := "properties:\n  port:\n    maximum: 65535\n    type: integer\n  host:\n    type: string\nrequired: [port]\ntype: object\n"
+	if err := repo.SaveConfigSchema(ctx, key, schema); err != nil {
+		t.Fatalf("SaveConfigSchema: %v", err)
+	}
+
+	results, err := repo.ValidateConfigs(ctx, key, [][]byte{
+		[]byte("port: 8080\nhost: localhost\n"),
+		[]byte(`{"port": 443}`),
+		[]byte("port: 70000\n"),
+		[]byte("host: 1.2.3.4\n"),
+		[]byte("port: [unclosed\n"),
+	})
+	if err != nil {
+		t.Fatalf("ValidateConfigs: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("got %d results, want 5", len(results))
+	}
+	for i := 0; i < 2; i++ {
+		if results[i] == nil || len(results[i]) != 0 {
+			t.Errorf("config %d: got %v, want no errors", i, results[i])
+		}
+	}
+	for i, field := range map[int]string{2: "port", 3: "(root)", 4: "(root)"} {
+		if len(results[i]) != 1 || results[i][0].Field != field {
+			t.Errorf("config %d: got %v, want one error on %s", i, results[i], field)
+		}
+	}
+	if !strings.Contains(results[4][0].Description, ErrInvalidConfig.Error()) {
+		t.Errorf("unparseable config: got %q", results[4][0].Description)
+	}
+
+	if _, err := repo.ValidateConfigs(ctx, "org/ns/name/v2.0.0", [][]byte{[]byte("port: 1\n")}); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("missing schema: got %v, want ErrSchemaNotFound", err)
+	}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// FirstPatchVersion is assigned by SaveNextPatchVersion when a schema has
+// no versions yet.
+const FirstPatchVersion = "v0.0.1"
+
+// FirstMinorVersion is assigned by SaveNextMinorVersion when a schema has
+// no versions yet.
+const FirstMinorVersion = "v0.1.0"
+
+// FirstMajorVersion is assigned by SaveNextMajorVersion when a schema has
+// no versions yet.
+const FirstMajorVersion = "v1.0.0"
+
+func (repo *EtcdRepository) SaveNextPatchVersion(ctx context.Context, org, namespace, name, schema string) (string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.SaveNextPatchVersion")
+	defer span.End()
+
+	if repo.readOnly {
+		return "", ErrReadOnly
+	}
+	return repo.saveNextVersion(ctx, org, namespace, name, schema, 2, FirstPatchVersion)
+}
+
+func (repo *EtcdRepository) SaveNextMinorVersion(ctx context.Context, org, namespace, name, schema string) (string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.SaveNextMinorVersion")
+	defer span.End()
+
+	if repo.readOnly {
+		return "", ErrReadOnly
+	}
+	return repo.saveNextVersion(ctx, org, namespace, name, schema, 1, FirstMinorVersion)
+}
+
+func (repo *EtcdRepository) SaveNextMajorVersion(ctx context.Context, org, namespace, name, schema string) (string, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.SaveNextMajorVersion")
+	defer span.End()
+
+	if repo.readOnly {
+		return "", ErrReadOnly
+	}
+	return repo.saveNextVersion(ctx, org, namespace, name, schema, 0, FirstMajorVersion)
+}
+
+func (repo *EtcdRepository) saveNextVersion(ctx context.Context, org, namespace, name, schema string, component int, first string) (string, error) {
+	latest, err := repo.GetLatestVersionByPrefix(ctx, repo.getSchemaPrefix(org, namespace, name))
+	if err != nil {
+		return "", err
+	}
+	version := first
+	if latest != "" {
+		version, err = nextVersion(latest, component)
+		if err != nil {
+			return "", err
+		}
+	}
+	if err := repo.SaveConfigSchema(ctx, repo.getSchemaKey(org, namespace, name, version), schema); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// nextVersion increments the given semver component (0 major, 1 minor,
+// 2 patch) of version, zeroing the lower ones and dropping any pre-release
+// or build suffix.
+func nextVersion(version string, component int) (string, error) {
+	canonical := semver.Canonical(version)
+	if canonical == "" {
+		return "", fmt.Errorf("version '%s' is not a valid SemVer string", version)
+	}
+	canonical = strings.TrimSuffix(canonical, semver.Prerelease(canonical))
+	var parts [3]int
+	for i, token := range strings.Split(strings.TrimPrefix(canonical, "v"), ".") {
+		value, err := strconv.Atoi(token)
+		if err != nil {
+			return "", fmt.Errorf("version '%s' is not a valid SemVer string", version)
+		}
+		parts[i] = value
+	}
+	return bumpVersion(parts, component), nil
+}
+
+// IsLatestVersion reports whether version is the newest stored version of
+// the schema. A schema without versions has no latest one, so the result
+// is false without an error.
+func (repo *EtcdRepository) IsLatestVersion(ctx context.Context, org, namespace, name, version string) (bool, error) {
+	ctx, span := repo.startSpan(ctx, "Repository.IsLatestVersion")
+	defer span.End()
+
+	latest, err := repo.GetLatestVersionByPrefix(ctx, repo.getSchemaPrefix(org, namespace, name))
+	if err != nil {
+		return false, err
+	}
+	return latest != "" && latest == version, nil
+}
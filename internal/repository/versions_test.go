@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSaveNextPatchVersion(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+
+	version, err := repo.SaveNextPatchVersion(ctx, "org", "ns", "first", testSchema)
+	if err != nil || version != FirstPatchVersion {
+		t.Fatalf("no versions: got %q, %v, want %s", version, err, FirstPatchVersion)
+	}
+	if fake.get("org/ns/first/"+FirstPatchVersion) == nil {
+		t.Errorf("%s was not written", FirstPatchVersion)
+	}
+
+	saveVersions(t, repo, "org/ns/name/", "v1.2.8", "v1.2.9")
+	version, err = repo.SaveNextPatchVersion(ctx, "org", "ns", "name", testSchema)
+	if err != nil || version != "v1.2.10" {
+		t.Fatalf("got %q, %v, want v1.2.10", version, err)
+	}
+	if fake.get("org/ns/name/v1.2.10") == nil {
+		t.Errorf("v1.2.10 was not written")
+	}
+}
+
+func TestSaveNextMinorAndMajorVersion(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx := context.Background()
+	for _, test := range []struct {
+		name string
+		save func(ctx context.Context, org, namespace, name, schema string) (string, error)
+		want string
+	}{
+		{"minor", repo.SaveNextMinorVersion, "v1.3.0"},
+		{"major", repo.SaveNextMajorVersion, "v2.0.0"},
+	} {
+		saveVersions(t, repo, "org/ns/"+test.name+"/", "v1.2.3", "v1.0.0")
+		version, err := test.save(ctx, "org", "ns", test.name, testSchema)
+		if err != nil || version != test.want {
+			t.Errorf("%s off v1.2.3: got %q, %v, want %s", test.name, version, err, test.want)
+		}
+		if fake.get("org/ns/"+test.name+"/"+test.want) == nil {
+			t.Errorf("%s: %s was not written", test.name, test.want)
+		}
+	}
+
+	for first, save := range map[string]func(ctx context.Context, org, namespace, name, schema string) (string, error){
+		FirstMinorVersion: repo.SaveNextMinorVersion,
+		FirstMajorVersion: repo.SaveNextMajorVersion,
+	} {
+		name := "first-" + first
+		if version, err := save(ctx, "org", "ns", name, testSchema); err != nil || version != first {
+			t.Errorf("no versions: got %q, %v, want %s", version, err, first)
+		}
+	}
+}
+
+func TestIsLatestVersion(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	saveVersions(t, repo, "org/ns/name/", "v1.9.0", "v1.10.0")
+	// A schema whose name extends the other's must not count.
+	mustSave(t, repo, "org/ns/name-next/v2.0.0")
+
+	for version, want := range map[string]bool{
+		"v1.10.0": true,
+		"v1.9.0":  false,
+		"v2.0.0":  false,
+		"":        false,
+	} {
+		if latest, err := repo.IsLatestVersion(ctx, "org", "ns", "name", version); err != nil || latest != want {
+			t.Errorf("%q: got %t, %v, want %t", version, latest, err, want)
+		}
+	}
+	for _, name := range []string{"", "missing"} {
+		if latest, err := repo.IsLatestVersion(ctx, "org", "ns", name, "v1.10.0"); err != nil || latest {
+			t.Errorf("name %q without versions: got %t, %v, want false", name, latest, err)
+		}
+	}
+
+	mustSave(t, repo, "org/ns/name/v1.11.0")
+	if latest, err := repo.IsLatestVersion(ctx, "org", "ns", "name", "v1.10.0"); err != nil || latest {
+		t.Errorf("superseded version: got %t, %v, want false", latest, err)
+	}
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/jtomic1/config-schema-service/proto"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var watchRetryInterval = time.Second
+
+type SchemaEventType int
+
+const (
+	SchemaPut SchemaEventType = iota
+	SchemaDeleted
+	// SchemaResync is emitted after the watch was re-established following
+	// a disconnect; Revision holds the last revision observed before it.
+	SchemaResync
+)
+
+type SchemaEvent struct {
+	Type     SchemaEventType
+	Key      string
+	Schema   *pb.ConfigSchema
+	Revision int64
+	// Err is only set on the last event before the channel is closed, when
+	// the watch cannot be resumed (e.g. the revision has been compacted).
+	Err error
+}
+
+// WatchSchemas streams changes under prefix until ctx is canceled. Dropped
+// watches are reopened from the revision after the last one observed, so no
+// events are lost across reconnects. Deleted schemas only carry details.
+func (repo *EtcdRepository) WatchSchemas(ctx context.Context, prefix string) <-chan SchemaEvent {
+	events := make(chan SchemaEvent)
+	go repo.watchSchemas(ctx, prefix, events)
+	return events
+}
+
+// WatchDeletes streams the details of schemas deleted under prefix until
+// ctx is canceled. Puts are filtered out by etcd and never transferred.
+func (repo *EtcdRepository) WatchDeletes(ctx context.Context, prefix string) (<-chan *pb.ConfigSchemaDetails, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	events := make(chan SchemaEvent)
+	go repo.watchSchemas(ctx, prefix, events, clientv3.WithFilterPut())
+	deletes := make(chan *pb.ConfigSchemaDetails)
+	go func() {
+		defer close(deletes)
+		for event := range events {
+			if event.Err != nil {
+				repo.logger.WarnContext(ctx, "delete watch stopped", "prefix", prefix, "error", event.Err)
+			}
+			if event.Type != SchemaDeleted || event.Err != nil {
+				continue
+			}
+			select {
+			case deletes <- event.Schema.GetSchemaDetails():
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return deletes, nil
+}
+
+func (repo *EtcdRepository) watchSchemas(ctx context.Context, prefix string, events chan<- SchemaEvent, filters ...clientv3.OpOption) {
+	defer close(events)
+	send := func(event SchemaEvent) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	if err := repo.checkTenant(ctx, prefix); err != nil {
+		send(SchemaEvent{Err: err})
+		return
+	}
+	var lastRevision int64
+	for {
+		watchOpts := append([]clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithCreatedNotify(), clientv3.WithProgressNotify()}, filters...)
+		if lastRevision > 0 {
+			watchOpts = append(watchOpts, clientv3.WithRev(lastRevision+1))
+		}
+		watchCh := repo.namespacedWatcher(repo.etcdClient()).Watch(clientv3.WithRequireLeader(ctx), prefix, watchOpts...)
+		for res := range watchCh {
+			if res.CompactRevision != 0 {
+				send(SchemaEvent{
+					Revision: lastRevision,
+					Err:      &WatchCompactedError{Revision: lastRevision + 1, CompactRevision: res.CompactRevision},
+				})
+				return
+			}
+			if err := res.Err(); err != nil {
+				repo.logger.WarnContext(ctx, "schema watch interrupted", "prefix", prefix, "error", err)
+				break
+			}
+			// The first watch starts after the revision in its created
+			// notification, so resuming from there loses nothing even if it
+			// drops before any event. Later created notifications report
+			// the current revision rather than the one resumed from; only
+			// progress notifications prove every earlier event was delivered.
+			if res.Created && lastRevision == 0 {
+				lastRevision = res.Header.Revision
+			}
+			if res.IsProgressNotify() && res.Header.Revision > lastRevision {
+				lastRevision = res.Header.Revision
+			}
+			for _, ev := range res.Events {
+				lastRevision = ev.Kv.ModRevision
+				event := SchemaEvent{Key: string(ev.Kv.Key), Revision: ev.Kv.ModRevision}
+				if repo.isReservedKey(event.Key) {
+					continue
+				}
+				if ev.Type == clientv3.EventTypeDelete {
+					details, err := repo.getSchemaDetailsFromKey(event.Key)
+					if err != nil {
+						continue
+					}
+					event.Type = SchemaDeleted
+					event.Schema = &pb.ConfigSchema{SchemaDetails: details}
+				} else {
+					schema, err := repo.decodeConfigSchema(ev.Kv)
+					if err != nil {
+						repo.logger.WarnContext(ctx, "skipping undecodable schema in watch", "key", event.Key, "error", err)
+						continue
+					}
+					event.Type = SchemaPut
+					event.Schema = schema
+				}
+				if !send(event) {
+					return
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case <-time.After(watchRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+		if !send(SchemaEvent{Type: SchemaResync, Revision: lastRevision}) {
+			return
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, events <-chan SchemaEvent) SchemaEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatalf("the event channel was closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no event arrived")
+	}
+	return SchemaEvent{}
+}
+
+func fastWatchRetries(t *testing.T) {
+	interval := watchRetryInterval
+	watchRetryInterval = 10 * time.Millisecond
+	t.Cleanup(func() { watchRetryInterval = interval })
+}
+
+func TestWatchSchemasResumesAfterDrop(t *testing.T) {
+	fastWatchRetries(t)
+	repo, fake := newTestRepo(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := repo.WatchSchemas(ctx, "org/")
+	for fake.callCount("Watch") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	first := nextEvent(t, events)
+	if first.Type != SchemaPut || first.Key != "org/ns/name/v1.0.0" {
+		t.Fatalf("got %+v", first)
+	}
+	fake.dropWatches("etcdserver: no leader")
+	// Saved while the watch is down; it must still be delivered.
+	mustSave(t, repo, "org/ns/name/v1.1.0")
+
+	resync := nextEvent(t, events)
+	if resync.Type != SchemaResync || resync.Revision != first.Revision {
+		t.Fatalf("got %+v, want a resync at revision %d", resync, first.Revision)
+	}
+	resumed := nextEvent(t, events)
+	if resumed.Type != SchemaPut || resumed.Key != "org/ns/name/v1.1.0" || resumed.Revision <= first.Revision {
+		t.Errorf("got %+v, want the put of v1.1.0", resumed)
+	}
+}
+
+func TestWatchSchemasResumesAfterDropBeforeAnyEvent(t *testing.T) {
+	fastWatchRetries(t)
+	repo, fake := newTestRepo(t)
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := repo.WatchSchemas(ctx, "org/")
+	for fake.openWatches() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	created := fake.currentRevision()
+
+	fake.dropWatches("etcdserver: no leader")
+	// Saved while the watch is down; it must still be delivered.
+	mustSave(t, repo, "org/ns/name/v1.1.0")
+
+	resync := nextEvent(t, events)
+	if resync.Type != SchemaResync || resync.Revision != created {
+		t.Fatalf("got %+v, want a resync at revision %d", resync, created)
+	}
+	resumed := nextEvent(t, events)
+	if resumed.Type != SchemaPut || resumed.Key != "org/ns/name/v1.1.0" {
+		t.Errorf("got %+v, want the put of v1.1.0", resumed)
+	}
+}
+
+func TestWatchSchemasReportsCompaction(t *testing.T) {
+	fastWatchRetries(t)
+	repo, fake := newTestRepo(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := repo.WatchSchemas(ctx, "org/")
+	for fake.callCount("Watch") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	mustSave(t, repo, "org/ns/name/v1.0.0")
+	first := nextEvent(t, events)
+	fake.dropWatches("etcdserver: no leader")
+	mustSave(t, repo, "org/ns/name/v1.1.0")
+	mustSave(t, repo, "org/ns/name/v1.2.0")
+	if _, err := repo.etcdClient().Compact(context.Background(), fake.currentRevision()); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	if resync := nextEvent(t, events); resync.Type != SchemaResync {
+		t.Fatalf("got %+v, want a resync", resync)
+	}
+	last := nextEvent(t, events)
+	var compacted *WatchCompactedError
+	if !errors.As(last.Err, &compacted) || compacted.Revision != first.Revision+1 {
+		t.Fatalf("got %+v, want a compaction error resuming from %d", last, first.Revision+1)
+	}
+	if _, ok := <-events; ok {
+		t.Errorf("the channel stayed open after the compaction error")
+	}
+}
+
+func TestWatchDeletesReportsOnlyDeletions(t *testing.T) {
+	repo, fake := newTestRepo(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	deletes, err := repo.WatchDeletes(ctx, "org/")
+	if err != nil {
+		t.Fatalf("WatchDeletes: %v", err)
+	}
+	for fake.callCount("Watch") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	next := func() string {
+		t.Helper()
+		select {
+		case details, ok := <-deletes:
+			if !ok {
+				t.Fatalf("the deletes channel was closed")
+			}
+			return details.GetOrganization() + "/" + details.GetNamespace() + "/" + details.GetSchemaName() + "/" + details.GetVersion()
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no deletion arrived")
+		}
+		return ""
+	}
+
+	saveVersions(t, repo, "org/ns/name/", "v1.0.0", "v1.1.0")
+	if err := repo.DeleteConfigSchema(context.Background(), "org/ns/name/v1.0.0"); err != nil {
+		t.Fatalf("DeleteConfigSchema: %v", err)
+	}
+	if got := next(); got != "org/ns/name/v1.0.0" {
+		t.Errorf("got %s, want org/ns/name/v1.0.0", got)
+	}
+	mustSave(t, repo, "org/ns/other/v1.0.0")
+	if err := repo.DeleteConfigSchema(context.Background(), "org/ns/other/v1.0.0"); err != nil {
+		t.Fatalf("DeleteConfigSchema: %v", err)
+	}
+	if got := next(); got != "org/ns/other/v1.0.0" {
+		t.Errorf("got %s, want org/ns/other/v1.0.0", got)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-deletes:
+		if ok {
+			t.Errorf("got a deletion after cancel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the deletes channel was not closed on cancel")
+	}
+}